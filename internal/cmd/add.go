@@ -22,7 +22,15 @@ var addCmd = &cobra.Command{
 Examples:
   gocrete add db --type postgres
   gocrete add openapi --mode gen --spec api.yaml
-  gocrete add docker`,
+  gocrete add docker
+  gocrete add outbox
+  gocrete add worker
+  gocrete add auth --type jwt
+  gocrete add auth --type oidc
+  gocrete add auth --type apikey
+  gocrete add rbac
+  gocrete add metrics
+  gocrete add tracing`,
 	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		moduleName := args[0]
@@ -55,7 +63,7 @@ Examples:
 }
 
 func init() {
-	addCmd.Flags().StringVar(&addType, "type", "", "Module type (for db: postgres|mongo)")
+	addCmd.Flags().StringVar(&addType, "type", "", "Module type (for db: postgres|mongo, for auth: jwt|oidc|apikey)")
 	addCmd.Flags().StringVar(&addMode, "mode", "", "Module mode (for openapi: gen|manual)")
 	addCmd.Flags().StringVar(&addSpec, "spec", "", "Spec path (for openapi gen)")
 }
@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/TRiZKy/gocrete/internal/engine"
+	"github.com/spf13/cobra"
+)
+
+var (
+	generateSpec    string
+	generateOutput  string
+	generatePackage string
+
+	generateSpecOutput string
+	generateSpecCheck  bool
+)
+
+var generateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: "Generate code from the project's OpenAPI spec, or the spec from the code",
+}
+
+var generateClientCmd = &cobra.Command{
+	Use:   "client",
+	Short: "Generate a Go client for the project's API",
+	Long: `Generate a Go client package from the project's OpenAPI spec.
+
+The package has a typed method per operation, retries with backoff, auth
+header injection and a typed error for the service's problem responses. It
+also contains NewHandler, which serves an implementation of the API's
+interface, and tests of the client against it.
+
+Examples:
+  gocrete generate client
+  gocrete generate client --spec api/openapi.yaml --output pkg/client
+  gocrete generate client --output pkg/usersapi --package usersapi`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Check if we're in a project directory
+		if _, err := os.Stat("go.mod"); os.IsNotExist(err) {
+			return fmt.Errorf("not in a Go project directory (go.mod not found)")
+		}
+
+		eng := engine.NewEngine()
+
+		opts := engine.GenerateOptions{
+			Spec:    generateSpec,
+			Output:  generateOutput,
+			Package: generatePackage,
+		}
+
+		if err := eng.GenerateClient(".", opts); err != nil {
+			return fmt.Errorf("failed to generate client: %w", err)
+		}
+
+		fmt.Printf("\n✓ Client generated in %s\n", generateOutput)
+
+		return nil
+	},
+}
+
+var generateSpecCmd = &cobra.Command{
+	Use:   "spec",
+	Short: "Generate the OpenAPI spec of a manual mode project",
+	Long: `Generate an OpenAPI 3.1 spec from the routes registered in
+internal/http/server.go and from their handlers.
+
+Request bodies, parameters and responses are found by reading the
+handlers' code: the structs they bind and validate, the path and query
+parameters they read and the values and statuses they write. Annotations in
+the handlers' doc comments, or in a comment above a route, complete them:
+
+  @summary Get a user
+  @description Returns the user with the given ID.
+  @tags users
+  @id getUser
+  @deprecated
+  @ignore
+  @param id path integer required The user ID
+  @body CreateUserRequest
+  @response 200 User The user
+  @response 204 -
+  @response 404 Problem
+
+With --check, the spec is not written and the command fails if it is out
+of date, e.g. in CI.
+
+Examples:
+  gocrete generate spec
+  gocrete generate spec --check`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Check if we're in a project directory
+		if _, err := os.Stat("go.mod"); os.IsNotExist(err) {
+			return fmt.Errorf("not in a Go project directory (go.mod not found)")
+		}
+
+		eng := engine.NewEngine()
+
+		opts := engine.GenerateOptions{
+			Output: generateSpecOutput,
+			Check:  generateSpecCheck,
+		}
+
+		if err := eng.GenerateSpec(".", opts); err != nil {
+			return fmt.Errorf("failed to generate spec: %w", err)
+		}
+
+		if generateSpecCheck {
+			fmt.Printf("\n✓ %s is up to date\n", generateSpecOutput)
+		} else {
+			fmt.Printf("\n✓ Spec generated in %s\n", generateSpecOutput)
+		}
+
+		return nil
+	},
+}
+
+var generateContractTestsCmd = &cobra.Command{
+	Use:   "contract-tests",
+	Short: "Generate tests of the server against the project's OpenAPI spec",
+	Long: `Generate contract tests of the server against api/openapi.yaml in
+internal/http.
+
+For each operation of the spec, contract_test.go sends an example request,
+taken from the spec's examples or derived from its schemas, to the router
+built by httpserver.NewServer, and checks that the response's status is
+documented and that its body matches the documented schema. Regenerate it
+when the spec changes; a test fails for operations without a test.
+
+contract_fixture_test.go configures the server with in-memory fakes, such
+as a test JWT verifier, and is only written if missing: edit it to give the
+server what your routes need.
+
+Examples:
+  gocrete generate contract-tests
+  go test ./internal/http -run Contract`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Check if we're in a project directory
+		if _, err := os.Stat("go.mod"); os.IsNotExist(err) {
+			return fmt.Errorf("not in a Go project directory (go.mod not found)")
+		}
+
+		eng := engine.NewEngine()
+
+		if err := eng.GenerateContractTests("."); err != nil {
+			return fmt.Errorf("failed to generate contract tests: %w", err)
+		}
+
+		fmt.Println("\n✓ Contract tests generated in internal/http")
+		fmt.Println("\nRun them with:")
+		fmt.Println("  go test ./internal/http -run Contract")
+
+		return nil
+	},
+}
+
+func init() {
+	generateClientCmd.Flags().StringVar(&generateSpec, "spec", "api/openapi.yaml", "OpenAPI spec to generate from")
+	generateClientCmd.Flags().StringVar(&generateOutput, "output", "pkg/client", "Output directory of the client package")
+	generateClientCmd.Flags().StringVar(&generatePackage, "package", "", "Package name (default: the output directory's name)")
+
+	generateSpecCmd.Flags().StringVar(&generateSpecOutput, "output", "api/openapi.yaml", "Spec file to write")
+	generateSpecCmd.Flags().BoolVar(&generateSpecCheck, "check", false, "Fail if the spec is out of date instead of writing it")
+
+	generateCmd.AddCommand(generateClientCmd)
+	generateCmd.AddCommand(generateSpecCmd)
+	generateCmd.AddCommand(generateContractTestsCmd)
+}
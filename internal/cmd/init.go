@@ -2,23 +2,29 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/TRiZKy/gocrete/internal/engine"
 	"github.com/TRiZKy/gocrete/internal/modules"
+	"github.com/TRiZKy/gocrete/internal/render"
+	"github.com/TRiZKy/gocrete/internal/routers"
 	"github.com/spf13/cobra"
 )
 
 var (
-	modulePath string
-	router     string
-	database   string
-	openapi    string
-	specPath   string
-	docker     bool
-	migrations string
-	force      bool
+	modulePath   string
+	router       string
+	database     string
+	openapi      string
+	specPath     string
+	docker       bool
+	migrations   string
+	force        bool
+	outputFormat string
+	output       string
 )
 
 var initCmd = &cobra.Command{
@@ -26,7 +32,7 @@ var initCmd = &cobra.Command{
 	Short: "Initialize a new Go project",
 	Long: `Initialize a new Go backend project with selected modules and capabilities.
 
-Example:
+Examples:
   gocrete init my-service \
     --module github.com/user/my-service \
     --router chi \
@@ -34,7 +40,13 @@ Example:
     --openapi gen \
     --spec ./api.yaml \
     --docker \
-    --migrations goose`,
+    --migrations goose
+
+  # Write the project as a tar archive to stdout
+  gocrete init my-service \
+    --module github.com/user/my-service \
+    --output-format tar \
+    --output - > my-service.tar`,
 	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		projectName := args[0]
@@ -48,14 +60,6 @@ Example:
 			return fmt.Errorf("--spec flag is required when using --openapi gen")
 		}
 
-		// Create project directory
-		projectPath := filepath.Join(".", projectName)
-
-		// Check if directory exists
-		if _, err := os.Stat(projectPath); err == nil && !force {
-			return fmt.Errorf("directory %s already exists (use --force to overwrite)", projectName)
-		}
-
 		// Create engine and initialize project
 		eng := engine.NewEngine()
 
@@ -71,6 +75,25 @@ Example:
 			Force:       force,
 		}
 
+		switch outputFormat {
+		case "dir":
+			if output != "" {
+				return fmt.Errorf("--output requires --output-format tar or zip")
+			}
+		case render.Tar, render.Zip:
+			return initArchive(eng, opts)
+		default:
+			return fmt.Errorf("invalid output format: %s (must be dir, tar, or zip)", outputFormat)
+		}
+
+		// Create project directory
+		projectPath := filepath.Join(".", projectName)
+
+		// Check if directory exists
+		if _, err := os.Stat(projectPath); err == nil && !force {
+			return fmt.Errorf("directory %s already exists (use --force to overwrite)", projectName)
+		}
+
 		fmt.Printf("Initializing project: %s\n", projectName)
 		fmt.Printf("Module path: %s\n", modulePath)
 
@@ -88,13 +111,64 @@ Example:
 	},
 }
 
+// initArchive writes the project as an archive to --output, a file named
+// after the project by default, or stdout for -.
+func initArchive(eng *engine.Engine, opts modules.InitOptions) error {
+	path := output
+	if path == "" {
+		path = opts.ProjectName + "." + outputFormat
+	}
+
+	var w io.Writer
+	var f *os.File
+	if path == "-" {
+		// Print the progress to stderr, to keep the archive stream clean
+		w = os.Stdout
+		eng.Progress = os.Stderr
+	} else {
+		if _, err := os.Stat(path); err == nil && !force {
+			return fmt.Errorf("file %s already exists (use --force to overwrite)", path)
+		}
+		var err error
+		f, err = os.Create(path)
+		if err != nil {
+			return fmt.Errorf("failed to create archive: %w", err)
+		}
+		w = f
+	}
+
+	fmt.Fprintf(eng.Progress, "Initializing project: %s\n", opts.ProjectName)
+	fmt.Fprintf(eng.Progress, "Module path: %s\n", opts.ModulePath)
+
+	err := eng.InitArchive(w, outputFormat, opts)
+	if f != nil {
+		// Close flushes the last writes, so its error means a partial archive
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(path)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to initialize project: %w", err)
+	}
+
+	if path != "-" {
+		fmt.Printf("\n✓ Project %s written to %s\n", opts.ProjectName, path)
+	}
+	return nil
+}
+
 func init() {
 	initCmd.Flags().StringVar(&modulePath, "module", "", "Go module path (required)")
-	initCmd.Flags().StringVar(&router, "router", "chi", "HTTP router (chi|gin|fiber)")
+	initCmd.Flags().StringVar(&router, "router", routers.Default, "HTTP router ("+strings.Join(routers.Names(), "|")+")")
 	initCmd.Flags().StringVar(&database, "db", "none", "Database type (none|postgres|mongo)")
 	initCmd.Flags().StringVar(&openapi, "openapi", "none", "OpenAPI mode (none|gen|manual)")
 	initCmd.Flags().StringVar(&specPath, "spec", "", "OpenAPI spec path (required if openapi=gen)")
 	initCmd.Flags().BoolVar(&docker, "docker", false, "Include Docker configuration")
 	initCmd.Flags().StringVar(&migrations, "migrations", "none", "Migration tool (none|goose)")
 	initCmd.Flags().BoolVar(&force, "force", false, "Overwrite existing directory")
+	initCmd.Flags().StringVar(&outputFormat, "output-format", "dir", "Output format (dir|tar|zip)")
+	initCmd.Flags().StringVar(&output, "output", "", "Archive path, - for stdout (default <project-name>.<output-format>)")
 }
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/TRiZKy/gocrete/internal/engine"
+	"github.com/TRiZKy/gocrete/internal/routers"
+	"github.com/spf13/cobra"
+)
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Migrate an existing project to other options",
+}
+
+var migrateRouterCmd = &cobra.Command{
+	Use:   "router <" + strings.Join(routers.Names(), "|") + ">",
+	Short: "Switch the project to another HTTP router",
+	Long: `Switch an existing Gocrete project to another HTTP router.
+
+The project's options are detected from its go.mod and the files gocrete
+generated. internal/http/server.go, cmd/server/main.go and the router
+specific files of the project's modules (middleware, request binding, error
+rendering and their tests) are generated again for the new router, along
+with the contract tests if the project has them.
+
+Generated files changed since they were generated are kept next to the new
+version with a .orig suffix, to port the changes, e.g. added routes.
+Handlers with the net/http shape, func(w http.ResponseWriter, r
+*http.Request), are left as they are and registered with the new router's
+adapter for them; the other files using the previous router are listed for
+manual migration.
+
+Commit your work first: the command rewrites files in place.
+
+Examples:
+  gocrete migrate router chi
+  gocrete migrate router gin`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Check if we're in a project directory
+		if _, err := os.Stat("go.mod"); os.IsNotExist(err) {
+			return fmt.Errorf("not in a Go project directory (go.mod not found)")
+		}
+
+		eng := engine.NewEngine()
+
+		if err := eng.MigrateRouter(".", args[0]); err != nil {
+			return fmt.Errorf("failed to migrate router: %w", err)
+		}
+
+		fmt.Printf("\n✓ Project migrated to %s\n", args[0])
+		fmt.Println("\nCheck the result with:")
+		fmt.Println("  go build ./... && go test ./...")
+
+		return nil
+	},
+}
+
+func init() {
+	migrateCmd.AddCommand(migrateRouterCmd)
+}
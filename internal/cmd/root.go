@@ -22,4 +22,6 @@ func Execute() error {
 func init() {
 	rootCmd.AddCommand(initCmd)
 	rootCmd.AddCommand(addCmd)
+	rootCmd.AddCommand(generateCmd)
+	rootCmd.AddCommand(migrateCmd)
 }
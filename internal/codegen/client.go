@@ -0,0 +1,150 @@
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/TRiZKy/gocrete/pkg/templates"
+)
+
+// clientTemplates is the directory of the client package's templates.
+const clientTemplates = "files/client"
+
+// ClientOptions configure GenerateClient.
+type ClientOptions struct {
+	SpecPath  string
+	OutputDir string
+	// Package is the name of the generated package. It defaults to the base
+	// name of OutputDir.
+	Package string
+}
+
+// GenerateClient generates a client package for the spec at opts.SpecPath
+// into opts.OutputDir, and returns the paths of the files it wrote.
+func GenerateClient(opts ClientOptions) ([]string, error) {
+	spec, err := LoadSpec(opts.SpecPath)
+	if err != nil {
+		return nil, err
+	}
+
+	pkg := opts.Package
+	if pkg == "" {
+		dir, err := filepath.Abs(opts.OutputDir)
+		if err != nil {
+			return nil, err
+		}
+		pkg = strings.ToLower(strings.NewReplacer("-", "", ".", "", "_", "").Replace(filepath.Base(dir)))
+	}
+	if !token.IsIdentifier(pkg) {
+		return nil, fmt.Errorf("invalid package name %q (set one with --package)", pkg)
+	}
+
+	api, err := Build(spec, pkg)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported spec: %w", err)
+	}
+	if len(api.Endpoints) == 0 {
+		return nil, fmt.Errorf("spec %s has no operations", opts.SpecPath)
+	}
+
+	files, err := RenderClient(api)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(opts.OutputDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	written := make([]string, 0, len(names))
+	for _, name := range names {
+		dest := filepath.Join(opts.OutputDir, name)
+		if err := os.WriteFile(dest, files[name], 0644); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", dest, err)
+		}
+		written = append(written, dest)
+	}
+	return written, nil
+}
+
+// RenderClient renders the files of a client package by name, formatted
+// with gofmt.
+func RenderClient(api *API) (map[string][]byte, error) {
+	return renderDir(clientTemplates, api, nil)
+}
+
+// renderDir renders the Go templates of a directory of templates.FS with
+// data, and returns the files by name, formatted with gofmt. The templates
+// can also call the functions of extra.
+func renderDir(dir string, data interface{}, extra template.FuncMap) (map[string][]byte, error) {
+	all := make(template.FuncMap, len(funcs)+len(extra))
+	for name, fn := range funcs {
+		all[name] = fn
+	}
+	for name, fn := range extra {
+		all[name] = fn
+	}
+
+	entries, err := fs.ReadDir(templates.FS, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string][]byte)
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".tmpl") {
+			continue
+		}
+		content, err := templates.FS.ReadFile(path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		tmpl, err := templates.Parse(entry.Name(), string(content), all)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse template %s: %w", entry.Name(), err)
+		}
+
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return nil, fmt.Errorf("failed to render template %s: %w", entry.Name(), err)
+		}
+		name := strings.TrimSuffix(entry.Name(), ".tmpl")
+		src, err := format.Source(buf.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("failed to format %s: %w", name, err)
+		}
+		files[name] = src
+	}
+	return files, nil
+}
+
+var funcs = template.FuncMap{
+	// title converts an HTTP method to the suffix of its net/http constant.
+	"title": func(s string) string {
+		return strings.ToUpper(s[:1]) + strings.ToLower(s[1:])
+	},
+	// quote quotes a string as a Go literal.
+	"quote": goString,
+	// comment formats text as the lines of a Go comment.
+	"comment": func(text string) string {
+		lines := strings.Split(strings.TrimSpace(text), "\n")
+		for i, line := range lines {
+			lines[i] = strings.TrimRight("// "+line, " ")
+		}
+		return strings.Join(lines, "\n")
+	},
+}
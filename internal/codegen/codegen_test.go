@@ -0,0 +1,739 @@
+package codegen
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+const testSpec = `
+openapi: 3.1.0
+info:
+  title: Pets
+  version: "1.0"
+paths:
+  /pets:
+    parameters:
+      - $ref: '#/components/parameters/Tenant'
+    get:
+      operationId: list_pets
+      summary: List pets
+      parameters:
+        - {name: limit, in: query, required: true, schema: {type: integer, format: int32}}
+        - {name: tag, in: query, schema: {type: array, items: {type: string}}}
+        - {name: X-Trace, in: header, schema: {type: boolean}}
+        - {name: session, in: cookie, schema: {type: string}}
+      responses:
+        200:
+          description: OK
+          content:
+            application/json:
+              schema:
+                type: object
+                required: [items]
+                properties:
+                  items: {type: array, items: {$ref: '#/components/schemas/Pet'}}
+                  next: {type: [string, "null"]}
+    post:
+      operationId: createPet
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema: {$ref: '#/components/schemas/Pet'}
+      responses:
+        201:
+          description: Created
+          content:
+            application/json:
+              schema: {$ref: '#/components/schemas/Pet'}
+  /pets/{type}:
+    delete:
+      parameters:
+        - {name: type, in: path, required: true, schema: {$ref: '#/components/schemas/Kind'}}
+      responses:
+        204: {description: Deleted}
+  /labels:
+    get:
+      operationId: labels
+      responses:
+        200:
+          description: OK
+          content:
+            application/json:
+              schema: {type: object, additionalProperties: {type: integer}}
+components:
+  parameters:
+    Tenant: {name: tenant, in: query, schema: {type: string}}
+  schemas:
+    Kind:
+      type: string
+      enum: [cat, guinea pig]
+    Pet:
+      type: object
+      required: [name]
+      properties:
+        name: {type: string}
+        kind: {$ref: '#/components/schemas/Kind'}
+        born: {type: string, format: date-time}
+        tags: {type: array, items: {type: string}}
+    Error:
+      type: object
+      properties:
+        message: {type: string}
+`
+
+func TestParseSpec(t *testing.T) {
+	spec, err := ParseSpec([]byte(testSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, prop := range spec.Components.Schemas["Pet"].Properties {
+		names = append(names, prop.Name)
+	}
+	if got := strings.Join(names, ","); got != "name,kind,born,tags" {
+		t.Errorf("properties = %s, want the spec's order", got)
+	}
+	next := spec.Paths["/pets"].Get.Responses["200"].Content["application/json"].Schema.Properties[1]
+	if next.Schema.Type != "string" {
+		t.Errorf("type of %s = %q, want string", next.Name, next.Schema.Type)
+	}
+
+	if _, err := ParseSpec([]byte("swagger: '2.0'")); err == nil {
+		t.Error("ParseSpec(swagger 2.0) succeeded")
+	}
+}
+
+func TestBuild(t *testing.T) {
+	spec, err := ParseSpec([]byte(testSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	api, err := Build(spec, "pets")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	endpoints := make(map[string]*Endpoint)
+	for _, e := range api.Endpoints {
+		endpoints[e.Name] = e
+	}
+	tests := []struct {
+		name      string
+		signature string
+		returns   string
+		pattern   string
+	}{
+		{name: "ListPets", signature: "ctx context.Context, params *ListPetsParams", returns: "(*ListPetsResponse, error)", pattern: "GET /pets"},
+		{name: "CreatePet", signature: "ctx context.Context, body Pet, params *CreatePetParams", returns: "(*Pet, error)", pattern: "POST /pets"},
+		{name: "DeletePetsByType", signature: "ctx context.Context, type_ string", returns: "error", pattern: "DELETE /pets/{Type}"},
+		{name: "Labels", signature: "ctx context.Context", returns: "(map[string]int64, error)", pattern: "GET /labels"},
+	}
+	for _, tt := range tests {
+		e, ok := endpoints[tt.name]
+		if !ok {
+			t.Errorf("no endpoint %s", tt.name)
+			continue
+		}
+		if got := e.Signature(); got != tt.signature {
+			t.Errorf("%s signature = %q, want %q", tt.name, got, tt.signature)
+		}
+		if got := e.Returns(); got != tt.returns {
+			t.Errorf("%s returns = %q, want %q", tt.name, got, tt.returns)
+		}
+		if e.Pattern != tt.pattern {
+			t.Errorf("%s pattern = %q, want %q", tt.name, e.Pattern, tt.pattern)
+		}
+	}
+
+	var params []string
+	for _, p := range endpoints["ListPets"].Params {
+		params = append(params, p.Name+" "+p.In+" "+p.Type)
+	}
+	if got, want := strings.Join(params, ", "), "Tenant query string, Limit query int32, Tag query []string, XTrace header bool"; got != want {
+		t.Errorf("ListPets params = %s, want %s", got, want)
+	}
+	if got := endpoints["DeletePetsByType"].PathExpr; got != `"/pets/" + url.PathEscape(fmt.Sprint(type_))` {
+		t.Errorf("DeletePetsByType path = %s", got)
+	}
+
+	types := make(map[string]*Type)
+	for _, typ := range api.Types {
+		types[typ.Name] = typ
+	}
+	if _, ok := types["Error2"]; !ok {
+		t.Error("schema Error was not renamed, it conflicts with the client's Error")
+	}
+	if kind := types["Kind"]; kind == nil || len(kind.Enum) != 2 || kind.Enum[1].Name != "KindGuineaPig" {
+		t.Errorf("Kind = %+v, want 2 constants", kind)
+	}
+	var fields []string
+	for _, f := range types["Pet"].Fields {
+		fields = append(fields, f.Name+" "+f.Type+" "+f.JSON)
+	}
+	if got, want := strings.Join(fields, ", "), "Name string name, Kind *Kind kind,omitempty, Born *time.Time born,omitempty, Tags []string tags,omitempty"; got != want {
+		t.Errorf("Pet fields = %s, want %s", got, want)
+	}
+}
+
+func TestBuildUnsupported(t *testing.T) {
+	tests := []struct {
+		name string
+		spec string
+	}{
+		{
+			name: "remote reference",
+			spec: `{openapi: 3.0.3, paths: {/a: {get: {responses: {200: {description: OK, content: {application/json: {schema: {$ref: 'other.yaml#/A'}}}}}}}}}`,
+		},
+		{
+			name: "undefined path parameter",
+			spec: `{openapi: 3.0.3, paths: {'/a/{id}': {get: {responses: {204: {description: OK}}}}}}`,
+		},
+		{
+			name: "object parameter",
+			spec: `{openapi: 3.0.3, paths: {/a: {get: {parameters: [{name: q, in: query, schema: {type: object}}], responses: {204: {description: OK}}}}}}`,
+		},
+		{
+			name: "XML body",
+			spec: `{openapi: 3.0.3, paths: {/a: {post: {requestBody: {content: {application/xml: {}}}, responses: {204: {description: OK}}}}}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec, err := ParseSpec([]byte(tt.spec))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := Build(spec, "client"); err == nil {
+				t.Error("Build() succeeded")
+			}
+		})
+	}
+}
+
+func TestRenderClient(t *testing.T) {
+	spec, err := ParseSpec([]byte(testSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	api, err := Build(spec, "pets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := RenderClient(api)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string][]string{
+		"client.go":      {"package pets", "func New(baseURL string, opts ...Option) *Client"},
+		"types.go":       {`"time"`, "type Pet struct", `KindGuineaPig Kind = "guinea pig"`},
+		"operations.go":  {"ListPets(ctx context.Context, params *ListPetsParams) (*ListPetsResponse, error)", `query.Set("limit", fmt.Sprint(params.Limit))`},
+		"server.go":      {`mux.HandleFunc("DELETE /pets/{Type}"`},
+		"client_test.go": {"func (f *fakeAPI) Labels(ctx context.Context) (map[string]int64, error)"},
+	}
+	for name, snippets := range want {
+		src, ok := files[name]
+		if !ok {
+			t.Errorf("no file %s", name)
+			continue
+		}
+		if !strings.HasPrefix(string(src), "// Code generated by gocrete generate client. DO NOT EDIT.") {
+			t.Errorf("%s has no generated code header", name)
+		}
+		for _, snippet := range snippets {
+			if !strings.Contains(string(src), snippet) {
+				t.Errorf("%s does not contain %s", name, snippet)
+			}
+		}
+	}
+}
+
+func TestContractCases(t *testing.T) {
+	spec, err := ParseSpec([]byte(testSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases, err := ContractCases(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, c := range cases {
+		got = append(got, c.Name()+" "+c.Target+" "+c.Body)
+	}
+	want := []string{
+		"GET /labels /labels ",
+		"GET /pets /pets?limit=1 ",
+		`POST /pets /pets {"name":"example"}`,
+		"DELETE /pets/{type} /pets/cat ",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("cases =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	files, err := RenderContractTests(cases, map[string]interface{}{
+		"ModulePath": "example.com/pets",
+		"Router":     "chi",
+		"OpenAPI":    "manual",
+		"HasJWT":     true,
+		"HasAPIKey":  false,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if src := string(files["contract_test.go"]); !strings.Contains(src, "target:  `/pets?limit=1`") {
+		t.Errorf("contract_test.go does not contain the cases:\n%s", src)
+	}
+	if src := string(files["contract_fixture_test.go"]); !strings.Contains(src, "httpserver.WithJWTVerifier(verifier)") || strings.Contains(src, "memoryKeyStore") {
+		t.Errorf("contract_fixture_test.go does not match the project's auth:\n%s", src)
+	}
+}
+
+// testProject is a chi project in manual OpenAPI mode, by file.
+var testProject = map[string]string{
+	"go.mod": "module example.com/shop\n\ngo 1.22\n",
+	"internal/http/server.go": `package http
+
+import (
+	"net/http"
+
+	"example.com/shop/internal/api/handlers"
+	"github.com/go-chi/chi/v5"
+)
+
+type Server struct{}
+
+func (s *Server) routes(h *handlers.Handlers) {
+	r := chi.NewRouter()
+	r.Get("/health", s.handleHealth)
+	r.Handle(docsPath, nil)
+	r.Route("/api/v1/orders", func(r chi.Router) {
+		r.Get("/", h.ListOrders)
+		r.With(nil).Post("/", h.CreateOrder)
+		// @tags admin
+		r.Delete("/{id:[0-9]+}", h.DeleteOrder)
+	})
+	r.Group(func(r chi.Router) {
+		r.Get("/internal", h.Internal)
+	})
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(` + "`" + `{"status":"healthy"}` + "`" + `))
+}
+`,
+	"internal/api/handlers/handlers.go": `package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"example.com/shop/internal/errors"
+	"example.com/shop/internal/validate"
+)
+
+type Handlers struct{}
+
+// Status is the status of an order.
+type Status string
+
+const (
+	StatusOpen   Status = "open"
+	StatusClosed Status = "closed"
+)
+
+// Order is an order.
+type Order struct {
+	ID     int64   ` + "`" + `json:"id"` + "`" + `
+	Status Status  ` + "`" + `json:"status"` + "`" + `
+	Note   *string ` + "`" + `json:"note,omitempty"` + "`" + `
+	Lines  []string ` + "`" + `json:"lines"` + "`" + `
+}
+
+// CreateOrderRequest is the body of CreateOrder.
+type CreateOrderRequest struct {
+	Item     string ` + "`" + `json:"item" validate:"required,max=50"` + "`" + `
+	Quantity int    ` + "`" + `json:"quantity" validate:"min=1"` + "`" + `
+}
+
+// ListOrders lists orders. The newest come first.
+func (h *Handlers) ListOrders(w http.ResponseWriter, r *http.Request) {
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	orders := make([]Order, 0, limit)
+	json.NewEncoder(w).Encode(orders)
+}
+
+// @summary Place an order
+// @response 409 Problem
+func (h *Handlers) CreateOrder(w http.ResponseWriter, r *http.Request) {
+	var req CreateOrderRequest
+	if err := validate.Bind(w, r, &req); err != nil {
+		errors.Write(w, r, err)
+		return
+	}
+	order := Order{ID: 1}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(order)
+}
+
+func (h *Handlers) DeleteOrder(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("If-Match") == "" {
+		errors.Write(w, r, errors.New(errors.CodeNotFound, "order not found"))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @ignore
+func (h *Handlers) Internal(w http.ResponseWriter, r *http.Request) {}
+`,
+}
+
+func TestExtractSpec(t *testing.T) {
+	dir := t.TempDir()
+	for name, src := range testProject {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	spec, err := ExtractSpec(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spec.Info.Title != "shop API" {
+		t.Errorf("title = %q, want shop API", spec.Info.Title)
+	}
+
+	var routes []string
+	for path, item := range spec.Paths {
+		for _, op := range item.Operations() {
+			routes = append(routes, op.Method+" "+path+" "+op.Operation.OperationID)
+		}
+	}
+	sort.Strings(routes)
+	want := "DELETE /api/v1/orders/{id} deleteOrder, GET /api/v1/orders listOrders, GET /health health, POST /api/v1/orders createOrder"
+	if got := strings.Join(routes, ", "); got != want {
+		t.Errorf("routes = %s, want %s", got, want)
+	}
+
+	list := spec.Paths["/api/v1/orders"].Get
+	if list.Summary != "Lists orders" || list.Description != "The newest come first." {
+		t.Errorf("ListOrders summary = %q, description = %q", list.Summary, list.Description)
+	}
+	if p := list.Parameters; len(p) != 1 || p[0].Name != "limit" || p[0].In != "query" || p[0].Schema.Type != "integer" {
+		t.Errorf("ListOrders parameters = %+v, want an integer limit", p)
+	}
+	if s := list.Responses["200"].Content["application/json"].Schema; s.Type != "array" || s.Items.Ref != "#/components/schemas/Order" {
+		t.Errorf("ListOrders response = %+v, want an array of Order", s)
+	}
+
+	create := spec.Paths["/api/v1/orders"].Post
+	if create.Summary != "Place an order" {
+		t.Errorf("CreateOrder summary = %q", create.Summary)
+	}
+	var statuses []string
+	for status := range create.Responses {
+		statuses = append(statuses, status)
+	}
+	sort.Strings(statuses)
+	if got := strings.Join(statuses, ","); got != "201,400,409,422,default" {
+		t.Errorf("CreateOrder responses = %s", got)
+	}
+	body := spec.Components.Schemas["CreateOrderRequest"]
+	if body == nil || body.AdditionalProperties == nil || body.AdditionalProperties.Allowed {
+		t.Errorf("CreateOrderRequest = %+v, want no additional properties", body)
+	} else if strings.Join(body.Required, ",") != "item" || *body.Properties[0].Schema.MaxLength != 50 || *body.Properties[1].Schema.Minimum != 1 {
+		t.Errorf("CreateOrderRequest does not have the constraints of its validate tags")
+	}
+	order := spec.Components.Schemas["Order"]
+	if strings.Join(order.Required, ",") != "id,status,lines" || len(order.Properties[1].Schema.Enum) != 2 {
+		t.Errorf("Order = %+v, want required id, status and lines, with an enum", order)
+	}
+	if lines := order.Properties[3].Schema; !lines.Nullable || order.Properties[1].Schema.Nullable {
+		t.Errorf("Order.lines = %+v, want a nullable slice", lines)
+	}
+
+	del := spec.Paths["/api/v1/orders/{id}"].Delete
+	if len(del.Tags) != 1 || del.Tags[0] != "admin" {
+		t.Errorf("DeleteOrder tags = %v, want the route's annotation", del.Tags)
+	}
+	if len(del.Parameters) != 2 || del.Parameters[0].In != "path" || del.Parameters[1].Name != "If-Match" {
+		t.Errorf("DeleteOrder parameters = %+v, want id and If-Match", del.Parameters)
+	}
+	if del.Responses["404"] == nil || del.Responses["204"] == nil {
+		t.Errorf("DeleteOrder responses = %v, want 204 and 404", del.Responses)
+	}
+
+	health := spec.Paths["/health"].Get.Responses["200"].Content["application/json"].Schema
+	if len(health.Properties) != 1 || health.Properties[0].Name != "status" {
+		t.Errorf("health response = %+v, want the written JSON's schema", health)
+	}
+
+	// The spec round trips, and a client can be built from it
+	data, err := MarshalSpec(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParseSpec(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Build(parsed, "shop"); err != nil {
+		t.Errorf("Build(extracted spec) error = %v", err)
+	}
+	again, err := MarshalSpec(parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(again) != string(data) {
+		t.Errorf("spec changed after a round trip:\n%s\nwant:\n%s", again, data)
+	}
+}
+
+func TestExtractSpecServeMux(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/notes\n\ngo 1.22\n",
+		"internal/http/server.go": `package http
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+type Server struct{}
+
+func (s *Server) routes(auth func(http.Handler) http.Handler) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /notes/{id}", s.getNote)
+	mux.Handle("DELETE /notes/{id}", auth(http.HandlerFunc(s.deleteNote)))
+	mux.Handle("GET /static/{path...}", http.NotFoundHandler())
+	mux.HandleFunc("/any", s.getNote)
+}
+
+// Note is a note.
+type Note struct {
+	Text string ` + "`" + `json:"text"` + "`" + `
+}
+
+// getNote returns a note.
+func (s *Server) getNote(w http.ResponseWriter, r *http.Request) {
+	_ = r.PathValue("id")
+	json.NewEncoder(w).Encode(Note{})
+}
+
+func (s *Server) deleteNote(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNoContent)
+}
+`,
+	}
+	for name, src := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	spec, err := ExtractSpec(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	item := spec.Paths["/notes/{id}"]
+	if len(spec.Paths) != 1 || item == nil || item.Get == nil || item.Delete == nil {
+		t.Fatalf("paths = %v, want GET and DELETE /notes/{id}", spec.Paths)
+	}
+	if item.Get.Summary != "Returns a note" || item.Get.Responses["200"].Content["application/json"].Schema.Ref != "#/components/schemas/Note" {
+		t.Errorf("GET /notes/{id} = %+v, want getNote's summary and response", item.Get)
+	}
+	if item.Delete.Responses["204"] == nil {
+		t.Errorf("DELETE /notes/{id} responses = %v, want the wrapped handler's 204", item.Delete.Responses)
+	}
+}
+
+func TestExtractSpecEcho(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/notes\n\ngo 1.22\n",
+		"internal/http/server.go": `package http
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+type Server struct{}
+
+func (s *Server) routes(auth echo.MiddlewareFunc) {
+	e := echo.New()
+	api := e.Group("/api")
+	api.GET("/notes/:id", s.getNote, auth)
+	api.DELETE("/notes/:id", s.deleteNote)
+}
+
+// getNote returns a note.
+func (s *Server) getNote(c echo.Context) error {
+	_ = c.Param("id")
+	_ = c.QueryParam("fields")
+	return c.JSON(http.StatusOK, echo.Map{"text": "hello"})
+}
+
+func (s *Server) deleteNote(c echo.Context) error {
+	return c.NoContent(http.StatusNoContent)
+}
+`,
+	}
+	for name, src := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	spec, err := ExtractSpec(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	item := spec.Paths["/api/notes/{id}"]
+	if item == nil || item.Get == nil || item.Delete == nil {
+		t.Fatalf("paths = %v, want GET and DELETE /api/notes/{id}", spec.Paths)
+	}
+	if item.Get.Summary != "Returns a note" || item.Get.Responses["200"] == nil {
+		t.Errorf("GET /api/notes/{id} = %+v, want getNote rather than the middleware after it", item.Get)
+	}
+	var query bool
+	for _, p := range item.Get.Parameters {
+		query = query || p.In == "query" && p.Name == "fields"
+	}
+	if !query {
+		t.Errorf("GET /api/notes/{id} parameters = %+v, want the fields query parameter", item.Get.Parameters)
+	}
+	if item.Delete.Responses["204"] == nil {
+		t.Errorf("DELETE /api/notes/{id} responses = %v, want 204", item.Delete.Responses)
+	}
+}
+
+func TestExtractSpecAuth(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/app\n\ngo 1.22\n",
+		"internal/http/server.go": `package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"example.com/app/internal/auth"
+	"github.com/go-chi/chi/v5"
+)
+
+type Server struct{}
+
+func (s *Server) routes() {
+	r := chi.NewRouter()
+	r.Get("/auth/login", auth.Login)
+	r.With(auth.RequireUser).Get("/api/v1/me", s.handleMe)
+	r.Method(http.MethodGet, "/admin", auth.RequireUser(http.HandlerFunc(s.handleMe)))
+}
+
+func (s *Server) handleMe(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(map[string]string{"subject": "user"})
+}
+`,
+		"internal/auth/auth.go": `package auth
+
+import (
+	"net/http"
+
+	apperrors "example.com/app/internal/errors"
+)
+
+// Login redirects to the provider.
+func Login(w http.ResponseWriter, r *http.Request) {
+	http.Redirect(w, r, "https://provider.test/authorize", http.StatusFound)
+}
+
+func RequireUser(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Cookie") == "" {
+			rejectAnonymous(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func rejectAnonymous(w http.ResponseWriter, r *http.Request) {
+	apperrors.Write(w, r, apperrors.New(apperrors.CodeUnauthenticated, "Login required"))
+}
+`,
+	}
+	for name, src := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	spec, err := ExtractSpec(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	login := spec.Paths["/auth/login"].Get
+	if found := login.Responses["302"]; found == nil || found.Headers["Location"] == nil || login.Responses["200"] != nil {
+		t.Errorf("GET /auth/login responses = %v, want a 302 with a Location header", login.Responses)
+	}
+	for _, p := range []string{"/api/v1/me", "/admin"} {
+		if op := spec.Paths[p].Get; op.Responses["401"] == nil || op.Responses["200"] == nil {
+			t.Errorf("GET %s responses = %v, want 200 and the middleware's 401", p, op.Responses)
+		}
+	}
+	if id := spec.Paths["/admin"].Get.OperationID; id != "getAdmin" {
+		t.Errorf("GET /admin operation ID = %s, want getAdmin", id)
+	}
+}
+
+func TestOpenAPIPath(t *testing.T) {
+	tests := []struct {
+		in, want string
+		ok       bool
+	}{
+		{in: "/users/{id}", want: "/users/{id}", ok: true},
+		{in: "/users/{id:[0-9]+}", want: "/users/{id}", ok: true},
+		{in: "/users/:id/posts/:post?", want: "/users/{id}/posts/{post}", ok: true},
+		{in: "/users/{$}", want: "/users/", ok: true},
+		{in: "/files/*path", ok: false},
+		{in: "/files/{path...}", ok: false},
+		{in: "/docs/*", ok: false},
+	}
+
+	for _, tt := range tests {
+		got, ok := openAPIPath(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("openAPIPath(%q) = %q, %v; want %q, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,340 @@
+package codegen
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/TRiZKy/gocrete/internal/routers"
+)
+
+// contractTemplates is the directory of the contract tests' templates.
+const contractTemplates = "files/contract"
+
+// ContractCase is the example request a contract test sends for an
+// operation.
+type ContractCase struct {
+	Method string
+	// Pattern is the path of the operation in the spec.
+	Pattern string
+	// Target is the path and query of the request.
+	Target string
+	Header []ContractHeader
+	// Body is the JSON body of the request, if any.
+	Body string
+}
+
+// ContractHeader is a header of an example request.
+type ContractHeader struct {
+	Name  string
+	Value string
+}
+
+// Name returns the name of the case's test.
+func (c *ContractCase) Name() string {
+	return c.Method + " " + c.Pattern
+}
+
+// ContractCases returns an example request for each operation of a spec,
+// in the order of their paths and methods. Parameters and bodies are taken
+// from the spec's examples, or derived from their schemas. Only required
+// query and header parameters are sent.
+func ContractCases(spec *Spec) ([]*ContractCase, error) {
+	b := &builder{spec: spec}
+	s := &sampler{spec: spec}
+
+	paths := make([]string, 0, len(spec.Paths))
+	for p := range spec.Paths {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	var cases []*ContractCase
+	for _, p := range paths {
+		item := spec.Paths[p]
+		for _, op := range item.Operations() {
+			c := &ContractCase{Method: op.Method, Pattern: p}
+			if err := s.parameters(b, c, item.Parameters, op.Operation.Parameters); err != nil {
+				return nil, fmt.Errorf("%s %s: %w", op.Method, p, err)
+			}
+			if op.Operation.RequestBody != nil {
+				body, err := b.requestBody(op.Operation.RequestBody)
+				if err != nil {
+					return nil, fmt.Errorf("%s %s: %w", op.Method, p, err)
+				}
+				if media, ok := body.Content["application/json"]; ok {
+					data, err := json.Marshal(jsonValue(s.media(media)))
+					if err != nil {
+						return nil, fmt.Errorf("%s %s: example body: %w", op.Method, p, err)
+					}
+					c.Body = string(data)
+				}
+			}
+			cases = append(cases, c)
+		}
+	}
+	return cases, nil
+}
+
+// RenderContractTests renders the contract tests of a project, by file
+// name. data is the project's template data, to which the cases are added
+// as Cases; the templates render the partials of its router.
+func RenderContractTests(cases []*ContractCase, data map[string]interface{}) (map[string][]byte, error) {
+	merged := make(map[string]interface{}, len(data)+1)
+	for k, v := range data {
+		merged[k] = v
+	}
+	merged["Cases"] = cases
+	router, _ := data["Router"].(string)
+	return renderDir(contractTemplates, merged, routers.Funcs(router))
+}
+
+// sampler derives example values from a spec.
+type sampler struct {
+	spec *Spec
+}
+
+// parameters sets the path, query and header parameters of c.
+func (s *sampler) parameters(b *builder, c *ContractCase, pathParams, opParams []*Parameter) error {
+	var merged []*Parameter
+	index := make(map[string]int)
+	for _, p := range append(append([]*Parameter{}, pathParams...), opParams...) {
+		p, err := b.parameter(p)
+		if err != nil {
+			return err
+		}
+		key := p.In + ":" + p.Name
+		if i, ok := index[key]; ok {
+			merged[i] = p
+			continue
+		}
+		index[key] = len(merged)
+		merged = append(merged, p)
+	}
+
+	target := c.Pattern
+	query := url.Values{}
+	for _, p := range merged {
+		if p.In != "path" && !p.Required {
+			continue
+		}
+		value := p.Example
+		if value == nil {
+			value = s.value(p.Schema, 0)
+		}
+		values := []string{fmt.Sprint(value)}
+		if list, ok := value.([]interface{}); ok {
+			values = values[:0]
+			for _, v := range list {
+				values = append(values, fmt.Sprint(v))
+			}
+		}
+		switch p.In {
+		case "path":
+			target = strings.ReplaceAll(target, "{"+p.Name+"}", url.PathEscape(values[0]))
+		case "query":
+			query[p.Name] = values
+		case "header":
+			c.Header = append(c.Header, ContractHeader{Name: p.Name, Value: strings.Join(values, ",")})
+		}
+	}
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	c.Target = target
+	return nil
+}
+
+// media returns the example of a media type: its example, its first named
+// example, or one derived from its schema.
+func (s *sampler) media(m *MediaType) interface{} {
+	if m.Example != nil {
+		return m.Example
+	}
+	names := make([]string, 0, len(m.Examples))
+	for name, example := range m.Examples {
+		if example != nil && example.Value != nil {
+			names = append(names, name)
+		}
+	}
+	if len(names) > 0 {
+		sort.Strings(names)
+		return m.Examples[names[0]].Value
+	}
+	return s.value(m.Schema, 0)
+}
+
+// maxDepth bounds the nesting of derived values, for recursive schemas.
+const maxDepth = 8
+
+// value derives a valid value from a schema: its example, default or first
+// enum value, or a value of its type within its constraints. Objects only
+// have their required properties, or all of them when none is.
+func (s *sampler) value(schema *Schema, depth int) interface{} {
+	if schema == nil || depth > maxDepth {
+		return nil
+	}
+	if schema.Ref != "" {
+		name, err := refName(schema.Ref, "schemas")
+		if err != nil {
+			return nil
+		}
+		return s.value(s.spec.Components.Schemas[name], depth+1)
+	}
+	switch {
+	case schema.Example != nil:
+		return schema.Example
+	case schema.Default != nil:
+		return schema.Default
+	case len(schema.Enum) > 0:
+		return schema.Enum[0]
+	case len(schema.AllOf) > 0:
+		merged := make(map[string]interface{})
+		for _, part := range schema.AllOf {
+			v, ok := s.value(part, depth+1).(map[string]interface{})
+			if !ok {
+				return s.value(part, depth+1)
+			}
+			for k, value := range v {
+				merged[k] = value
+			}
+		}
+		return merged
+	case len(schema.OneOf) > 0:
+		return s.value(schema.OneOf[0], depth+1)
+	case len(schema.AnyOf) > 0:
+		return s.value(schema.AnyOf[0], depth+1)
+	}
+
+	switch schema.Type {
+	case "string":
+		return sampleString(schema)
+	case "integer":
+		return int64(sampleNumber(schema, 1))
+	case "number":
+		return sampleNumber(schema, 1.5)
+	case "boolean":
+		return true
+	case "array":
+		n := 1
+		if schema.MinItems != nil && *schema.MinItems > n {
+			n = *schema.MinItems
+		}
+		if schema.MaxItems != nil && *schema.MaxItems < n {
+			n = *schema.MaxItems
+		}
+		items := make([]interface{}, 0, n)
+		for i := 0; i < n; i++ {
+			items = append(items, s.value(schema.Items, depth+1))
+		}
+		return items
+	case "object", "":
+		if schema.Type == "" && len(schema.Properties) == 0 {
+			return nil
+		}
+		required := make(map[string]bool)
+		for _, name := range schema.Required {
+			required[name] = true
+		}
+		obj := make(map[string]interface{})
+		for _, prop := range schema.Properties {
+			if len(required) > 0 && !required[prop.Name] {
+				continue
+			}
+			if v := s.value(prop.Schema, depth+1); v != nil {
+				obj[prop.Name] = v
+			}
+		}
+		return obj
+	}
+	return nil
+}
+
+// sampleString returns a string of a schema's format and length.
+func sampleString(schema *Schema) string {
+	var v string
+	switch schema.Format {
+	case "date-time":
+		v = "2024-01-01T00:00:00Z"
+	case "date":
+		v = "2024-01-01"
+	case "time":
+		v = "00:00:00"
+	case "email":
+		v = "user@example.com"
+	case "uuid":
+		v = "00000000-0000-4000-8000-000000000001"
+	case "uri", "url":
+		v = "https://example.com"
+	case "hostname":
+		v = "example.com"
+	case "ipv4":
+		v = "192.0.2.1"
+	case "ipv6":
+		v = "2001:db8::1"
+	case "byte":
+		v = "ZXhhbXBsZQ=="
+	default:
+		v = "example"
+	}
+	if schema.MinLength != nil && len(v) < *schema.MinLength {
+		v += strings.Repeat("x", *schema.MinLength-len(v))
+	}
+	if schema.MaxLength != nil && len(v) > *schema.MaxLength {
+		v = v[:*schema.MaxLength]
+	}
+	return v
+}
+
+// sampleNumber returns v, moved within a schema's bounds.
+func sampleNumber(schema *Schema, v float64) float64 {
+	if schema.Minimum != nil && v < *schema.Minimum {
+		v = *schema.Minimum
+	}
+	if schema.Maximum != nil && v > *schema.Maximum {
+		v = *schema.Maximum
+	}
+	if schema.Type == "integer" {
+		v = math.Ceil(v)
+	}
+	return v
+}
+
+// jsonValue converts the maps decoded by yaml.v3 from examples, whose keys
+// need not be strings, for encoding/json.
+func jsonValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			m[key] = jsonValue(value)
+		}
+		return m
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			m[fmt.Sprint(key)] = jsonValue(value)
+		}
+		return m
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, value := range v {
+			list[i] = jsonValue(value)
+		}
+		return list
+	default:
+		return v
+	}
+}
+
+// goString quotes s as a Go string literal, raw when it can be.
+func goString(s string) string {
+	if !strings.ContainsAny(s, "`\r") && strconv.CanBackquote(s) {
+		return "`" + s + "`"
+	}
+	return strconv.Quote(s)
+}
@@ -0,0 +1,653 @@
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/TRiZKy/gocrete/pkg/templates"
+	"gopkg.in/yaml.v3"
+)
+
+// ServerFile is the file whose route registrations ExtractSpec reads.
+const ServerFile = "internal/http/server.go"
+
+const problemRef = "#/components/responses/Problem"
+
+// extractor builds a spec from the routes of a project.
+type extractor struct {
+	src  *source
+	spec *Spec
+	// schemaNames are the component names of Go types, by package path
+	// and type name.
+	schemaNames map[string]string
+	opIDs       map[string]bool
+	problems    bool
+}
+
+// route is a route registration.
+type route struct {
+	method     string
+	path       string
+	handler    ast.Expr
+	middleware []ast.Expr
+	comment    string
+	file       *goFile
+}
+
+// ExtractSpec builds an OpenAPI 3.1 spec from the routes registered in the
+// project's server.go by chi, gin, fiber, echo or http.ServeMux, and from their
+// handlers: the request bodies they bind, the parameters they read and the
+// responses they write. Annotations in doc comments, such as @summary or
+// @response, complete or override what the code tells. The info of base, if
+// any, is kept.
+func ExtractSpec(projectPath string, base *Spec) (*Spec, error) {
+	fsys := os.DirFS(projectPath)
+	module, err := modulePath(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return ExtractSpecFS(fsys, module, base)
+}
+
+// ExtractSpecFS is ExtractSpec for the project of a module in fsys, such as
+// one being generated, which has no go.mod yet.
+func ExtractSpecFS(fsys fs.FS, module string, base *Spec) (*Spec, error) {
+	src := newSource(fsys, module)
+	server, err := src.file(ServerFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", ServerFile, err)
+	}
+
+	x := &extractor{
+		src:         src,
+		spec:        &Spec{OpenAPI: "3.1.0", Paths: make(map[string]*PathItem)},
+		schemaNames: make(map[string]string),
+		opIDs:       make(map[string]bool),
+	}
+	x.spec.Components.Schemas = make(map[string]*Schema)
+	if base != nil && base.Info.Title != "" {
+		x.spec.Info = base.Info
+	} else {
+		x.spec.Info.Title = path.Base(src.module) + " API"
+		x.spec.Info.Version = "1.0.0"
+	}
+
+	for _, r := range x.routes(server) {
+		op := x.operation(r)
+		if op == nil {
+			continue
+		}
+		item, ok := x.spec.Paths[r.path]
+		if !ok {
+			item = &PathItem{}
+			x.spec.Paths[r.path] = item
+		}
+		for _, existing := range item.Operations() {
+			if existing.Method == r.method {
+				// The first registration of a route wins, as in the routers
+				op = nil
+			}
+		}
+		if op != nil {
+			item.SetOperation(r.method, op)
+		}
+	}
+
+	if x.problems {
+		x.spec.Components.Schemas["Problem"] = problemSchema()
+		x.spec.Components.Responses = map[string]*Response{
+			"Problem": {
+				Description: "Error",
+				Content:     map[string]*MediaType{"application/problem+json": {Schema: schemaRef("Problem")}},
+			},
+		}
+	}
+	if len(x.spec.Components.Schemas) == 0 {
+		x.spec.Components.Schemas = nil
+	}
+	return x.spec, nil
+}
+
+// MarshalSpec encodes a spec built by ExtractSpec as YAML.
+func MarshalSpec(spec *Spec) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("# Generated by gocrete generate spec from the routes in " + ServerFile + ".\n")
+	buf.WriteString("# Document handlers with doc comments and annotations rather than editing this file.\n")
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(spec); err != nil {
+		return nil, fmt.Errorf("failed to encode spec: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// problemSchema is the schema of the problem details written by the
+// project's errors package.
+func problemSchema() *Schema {
+	str := func() *Schema { return &Schema{Type: "string"} }
+	return &Schema{
+		Type:        "object",
+		Description: "RFC 7807 problem details, see internal/errors",
+		Properties: Properties{
+			{Name: "type", Schema: str()},
+			{Name: "title", Schema: str()},
+			{Name: "status", Schema: &Schema{Type: "integer"}},
+			{Name: "detail", Schema: str()},
+			{Name: "instance", Schema: str()},
+			{Name: "code", Schema: str()},
+			{Name: "request_id", Schema: str()},
+			{Name: "errors", Schema: &Schema{Type: "array", Items: &Schema{
+				Type:       "object",
+				Properties: Properties{{Name: "field", Schema: str()}, {Name: "message", Schema: str()}},
+			}}},
+		},
+	}
+}
+
+// routeMethods maps the route registration methods of chi, gin, fiber and echo,
+// and the methods of http.ServeMux patterns, to their HTTP methods.
+var routeMethods = map[string]string{
+	"Get": "GET", "Post": "POST", "Put": "PUT", "Patch": "PATCH",
+	"Delete": "DELETE", "Head": "HEAD", "Options": "OPTIONS",
+	"GET": "GET", "POST": "POST", "PUT": "PUT", "PATCH": "PATCH",
+	"DELETE": "DELETE", "HEAD": "HEAD", "OPTIONS": "OPTIONS",
+}
+
+// routes returns the routes registered in the functions of f, in source
+// order.
+func (x *extractor) routes(f *goFile) []route {
+	// Comments ending on the line above a registration annotate it
+	comments := make(map[int]string)
+	for _, group := range f.ast.Comments {
+		comments[x.src.fset.Position(group.End()).Line] = group.Text()
+	}
+
+	// Echo takes route middleware after the handler, the others before
+	echoRoutes := f.importsPackage("github.com/labstack/echo/v4")
+
+	var routes []route
+	var walk func(body ast.Node, prefixes map[string]string)
+	walk = func(body ast.Node, prefixes map[string]string) {
+		ast.Inspect(body, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.AssignStmt:
+				// v1 := r.Group("/api/v1", mw)
+				if len(n.Lhs) == 1 && len(n.Rhs) == 1 {
+					if id, ok := n.Lhs[0].(*ast.Ident); ok {
+						if call, ok := n.Rhs[0].(*ast.CallExpr); ok && isCall(call, "Group") {
+							prefixes[id.Name] = prefixOf(call, prefixes)
+						}
+					}
+				}
+			case *ast.CallExpr:
+				sel, ok := n.Fun.(*ast.SelectorExpr)
+				if !ok {
+					return true
+				}
+				// r.Route("/users", func(r chi.Router) {...}) and r.Group(func(r chi.Router) {...})
+				if lit, param := subrouter(n); lit != nil && (sel.Sel.Name == "Route" || sel.Sel.Name == "Group") {
+					inner := make(map[string]string, len(prefixes)+1)
+					for k, v := range prefixes {
+						inner[k] = v
+					}
+					inner[param] = prefixOf(n, prefixes)
+					walk(lit.Body, inner)
+					return false
+				}
+				method, pattern, handlers, ok := registration(sel.Sel.Name, n.Args)
+				if !ok {
+					return true
+				}
+				handler, middleware := handlers[len(handlers)-1], handlers[:len(handlers)-1]
+				if echoRoutes {
+					handler, middleware = handlers[0], handlers[1:]
+				}
+				p, ok := openAPIPath(joinPath(prefixOf(sel.X, prefixes), pattern))
+				if !ok {
+					return true
+				}
+				routes = append(routes, route{
+					method:     method,
+					path:       p,
+					handler:    handler,
+					middleware: append(withMiddleware(sel.X), middleware...),
+					comment:    comments[x.src.fset.Position(n.Pos()).Line-1],
+					file:       f,
+				})
+			}
+			return true
+		})
+	}
+	for _, decl := range f.ast.Decls {
+		if fd, ok := decl.(*ast.FuncDecl); ok && fd.Body != nil {
+			walk(fd.Body, make(map[string]string))
+		}
+	}
+	return routes
+}
+
+// registration returns the method and path of a route registration, such
+// as r.Get("/users", h), r.Method("GET", "/users", h), r.Handle("GET",
+// "/users", h) or mux.HandleFunc("GET /users", h), and the arguments after
+// the path: the handler and its middleware. Registrations with paths that
+// are not literals, or without a method, are skipped.
+func registration(name string, args []ast.Expr) (string, string, []ast.Expr, bool) {
+	if (name == "Handle" || name == "HandleFunc") && len(args) == 2 {
+		pattern, ok := stringLit(args[0])
+		if !ok {
+			return "", "", nil, false
+		}
+		method, p, ok := strings.Cut(pattern, " ")
+		if !ok || routeMethods[method] != method || !strings.HasPrefix(p, "/") {
+			return "", "", nil, false
+		}
+		return method, p, args[1:], true
+	}
+	method, ok := routeMethods[name]
+	if !ok {
+		if name != "Method" && name != "Handle" && name != "Add" || len(args) < 3 {
+			return "", "", nil, false
+		}
+		if method = methodOf(args[0]); method == "" {
+			return "", "", nil, false
+		}
+		args = args[1:]
+	}
+	if len(args) < 2 {
+		return "", "", nil, false
+	}
+	pattern, ok := stringLit(args[0])
+	if !ok || !strings.HasPrefix(pattern, "/") {
+		return "", "", nil, false
+	}
+	return method, pattern, args[1:], true
+}
+
+// methodOf returns the HTTP method of a literal or an http.MethodX
+// constant.
+func methodOf(expr ast.Expr) string {
+	if s, ok := stringLit(expr); ok {
+		return strings.ToUpper(s)
+	}
+	if sel, ok := expr.(*ast.SelectorExpr); ok && strings.HasPrefix(sel.Sel.Name, "Method") {
+		return strings.ToUpper(strings.TrimPrefix(sel.Sel.Name, "Method"))
+	}
+	return ""
+}
+
+// withMiddleware returns the middleware of a router derived with
+// r.With(mw...), as chi does.
+func withMiddleware(expr ast.Expr) []ast.Expr {
+	var middleware []ast.Expr
+	for {
+		call, ok := expr.(*ast.CallExpr)
+		if !ok {
+			return middleware
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok {
+			return middleware
+		}
+		if sel.Sel.Name == "With" {
+			middleware = append(middleware, call.Args...)
+		}
+		expr = sel.X
+	}
+}
+
+// subrouter returns the function literal configuring a subrouter, and the
+// name of its router parameter.
+func subrouter(call *ast.CallExpr) (*ast.FuncLit, string) {
+	for _, arg := range call.Args {
+		lit, ok := arg.(*ast.FuncLit)
+		if !ok || len(lit.Type.Params.List) != 1 || len(lit.Type.Params.List[0].Names) != 1 {
+			continue
+		}
+		param := lit.Type.Params.List[0]
+		if sel, ok := param.Type.(*ast.SelectorExpr); ok && sel.Sel.Name == "Router" {
+			return lit, param.Names[0].Name
+		}
+	}
+	return nil, ""
+}
+
+// prefixOf returns the path prefix of a router expression: a variable
+// holding a group, or a call deriving a router from another, such as
+// r.With(mw) or app.Group("/api").
+func prefixOf(expr ast.Expr, prefixes map[string]string) string {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return prefixes[e.Name]
+	case *ast.CallExpr:
+		sel, ok := e.Fun.(*ast.SelectorExpr)
+		if !ok {
+			return ""
+		}
+		prefix := prefixOf(sel.X, prefixes)
+		if sel.Sel.Name == "Group" || sel.Sel.Name == "Route" {
+			if len(e.Args) > 0 {
+				if p, ok := stringLit(e.Args[0]); ok {
+					prefix = joinPath(prefix, p)
+				}
+			}
+		}
+		return prefix
+	}
+	return ""
+}
+
+func joinPath(prefix, p string) string {
+	if prefix == "" {
+		return p
+	}
+	prefix = strings.TrimSuffix(prefix, "/")
+	if p == "/" || p == "" {
+		return prefix
+	}
+	return prefix + p
+}
+
+var routeParam = regexp.MustCompile(`^(?::([A-Za-z0-9_]+)\??|\{([A-Za-z0-9_]+)(?::.*)?\})$`)
+
+// openAPIPath converts the path of a route to an OpenAPI path: /users/:id
+// and /users/{id:[0-9]+} become /users/{id}, and /users/{$} becomes
+// /users/. Routes with wildcards are not supported.
+func openAPIPath(p string) (string, bool) {
+	segments := strings.Split(p, "/")
+	for i, segment := range segments {
+		if strings.Contains(segment, "*") || strings.HasSuffix(segment, "...}") {
+			return "", false
+		}
+		if segment == "{$}" {
+			segments[i] = ""
+			continue
+		}
+		if m := routeParam.FindStringSubmatch(segment); m != nil {
+			segments[i] = "{" + m[1] + m[2] + "}"
+		}
+	}
+	return strings.Join(segments, "/"), true
+}
+
+// pathParams returns the names of the parameters of an OpenAPI path.
+func pathParams(p string) []string {
+	var names []string
+	for _, segment := range strings.Split(p, "/") {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			names = append(names, strings.Trim(segment, "{}"))
+		}
+	}
+	return names
+}
+
+// operation returns the operation of a route, or nil if it is ignored.
+func (x *extractor) operation(r route) *Operation {
+	h := x.resolveHandler(r.handler, r.file)
+	if h == nil {
+		h = &handler{file: r.file, locals: make(map[string]local)}
+	}
+	// Comments above routes, such as "// Routes", are only read for their
+	// annotations
+	comments := []string{h.doc}
+	for _, line := range strings.Split(r.comment, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "@") {
+			comments = append(comments, line)
+		}
+	}
+	notes := parseAnnotations(strings.Join(comments, "\n"))
+	if notes.ignore {
+		return nil
+	}
+	a := x.analyze(h)
+	for _, mw := range append(r.middleware, h.middleware...) {
+		for _, status := range x.middlewareStatuses(mw, r.file) {
+			a.problem(status)
+		}
+	}
+
+	op := &Operation{Tags: notes.tags, Deprecated: notes.deprecated, Responses: a.responses}
+	op.Summary, op.Description = operationSummary(h.name, strings.Join(notes.text, "\n"))
+	if notes.summary != "" {
+		op.Summary = notes.summary
+	}
+	if notes.description != "" {
+		op.Description = notes.description
+	}
+	op.OperationID = x.operationID(notes.id, h.name, r.method, r.path)
+
+	// Parameters: those of the path first, in order
+	params := make(map[string]*Parameter)
+	for _, p := range a.params {
+		params[p.In+" "+p.Name] = p
+	}
+	for _, n := range notes.params {
+		params[n.In+" "+n.Name] = n
+	}
+	for _, name := range pathParams(r.path) {
+		p, ok := params["path "+name]
+		if !ok {
+			p = &Parameter{Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"}}
+		}
+		op.Parameters = append(op.Parameters, p)
+		delete(params, "path "+name)
+	}
+	for _, p := range append(a.params, notes.params...) {
+		if params[p.In+" "+p.Name] == p && p.In != "path" {
+			op.Parameters = append(op.Parameters, p)
+			delete(params, p.In+" "+p.Name)
+		}
+	}
+
+	// Request body
+	body := a.body
+	if notes.body != "" {
+		body = x.annotatedSchema(notes.body, h.file)
+	}
+	if body != nil {
+		if name := strings.TrimPrefix(body.Ref, "#/components/schemas/"); a.strict && name != "" {
+			if s := x.spec.Components.Schemas[name]; s != nil && s.AdditionalProperties == nil {
+				s.AdditionalProperties = &Additional{}
+			}
+		}
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]*MediaType{"application/json": {Schema: body}},
+		}
+	}
+
+	// Responses
+	for _, n := range notes.responses {
+		switch {
+		case n.typ == "Problem":
+			op.Responses[n.status] = &Response{Ref: problemRef}
+		default:
+			resp := &Response{Description: n.description}
+			if resp.Description == "" {
+				code, _ := strconv.Atoi(n.status)
+				resp.Description = http.StatusText(code)
+			}
+			if resp.Description == "" {
+				resp.Description = "Response"
+			}
+			if n.typ != "-" {
+				resp.Content = map[string]*MediaType{"application/json": {Schema: x.annotatedSchema(n.typ, h.file)}}
+			}
+			op.Responses[n.status] = resp
+		}
+	}
+	if a.problems {
+		if _, ok := op.Responses["default"]; !ok {
+			op.Responses["default"] = &Response{Ref: problemRef}
+		}
+	}
+	success := false
+	for status, resp := range op.Responses {
+		if resp.Ref == problemRef {
+			x.problems = true
+		} else if strings.HasPrefix(status, "2") || strings.HasPrefix(status, "3") {
+			success = true
+		}
+	}
+	if !success {
+		// Handlers respond with 200 unless told otherwise
+		op.Responses["200"] = &Response{Description: http.StatusText(http.StatusOK)}
+	}
+	return op
+}
+
+// operationID returns a unique operation ID: the annotated one, or the
+// name of the handler without the handle prefix and router suffix, e.g.
+// health for handleHealthGin. When another route has the same handler, the
+// ID is derived from the method and path instead, e.g. getAdmin.
+func (x *extractor) operationID(id, handlerName, method, p string) string {
+	if id == "" {
+		name := strings.TrimPrefix(handlerName, "handle")
+		for _, suffix := range []string{"Gin", "Fiber"} {
+			name = strings.TrimSuffix(name, suffix)
+		}
+		if name == "" {
+			return ""
+		}
+		id = templates.GoIdent(name)
+		if x.opIDs[id] {
+			id = pathOperationID(method, p)
+		}
+	}
+	unique := id
+	for i := 2; x.opIDs[unique]; i++ {
+		unique = id + strconv.Itoa(i)
+	}
+	x.opIDs[unique] = true
+	return unique
+}
+
+// pathOperationID returns the operation ID of a method and path:
+// getUsersByID for GET /users/{id}.
+func pathOperationID(method, p string) string {
+	words := []string{strings.ToLower(method)}
+	for _, segment := range strings.Split(p, "/") {
+		if name := strings.Trim(segment, "{}"); name != segment {
+			words = append(words, "by", name)
+		} else if segment != "" {
+			words = append(words, segment)
+		}
+	}
+	return templates.GoIdent(strings.Join(words, " "))
+}
+
+// annotatedSchema returns the schema of a Go type named by an annotation,
+// such as User, []handlers.User or map[string]int.
+func (x *extractor) annotatedSchema(typ string, f *goFile) *Schema {
+	expr, err := parser.ParseExpr(typ)
+	if err != nil {
+		return &Schema{}
+	}
+	return x.typeSchema(expr, f)
+}
+
+// annotations are the @ lines of a handler's doc comment or of the comment
+// above its route. The other lines are kept as text.
+type annotations struct {
+	text        []string
+	summary     string
+	description string
+	id          string
+	tags        []string
+	deprecated  bool
+	ignore      bool
+	params      []*Parameter
+	body        string
+	responses   []annotatedResponse
+}
+
+type annotatedResponse struct {
+	status      string
+	typ         string
+	description string
+}
+
+// parseAnnotations parses the annotations of comments:
+//
+//	@summary Get a user
+//	@description Returns the user with the given ID.
+//	@tags users, admin
+//	@id getUser
+//	@deprecated
+//	@ignore
+//	@param id path integer required The user ID
+//	@body CreateUserRequest
+//	@response 200 User The user
+//	@response 204 -
+//	@response 404 Problem
+func parseAnnotations(comments string) annotations {
+	var a annotations
+	for _, line := range strings.Split(comments, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "@") {
+			a.text = append(a.text, line)
+			continue
+		}
+		key, value, _ := strings.Cut(line[1:], " ")
+		value = strings.TrimSpace(value)
+		fields := strings.Fields(value)
+		switch key {
+		case "summary":
+			a.summary = value
+		case "description":
+			a.description = value
+		case "id":
+			a.id = value
+		case "tags":
+			for _, tag := range strings.Split(value, ",") {
+				if tag = strings.TrimSpace(tag); tag != "" {
+					a.tags = append(a.tags, tag)
+				}
+			}
+		case "deprecated":
+			a.deprecated = true
+		case "ignore":
+			a.ignore = true
+		case "param":
+			if len(fields) < 3 {
+				continue
+			}
+			p := &Parameter{Name: fields[0], In: fields[1], Required: fields[1] == "path"}
+			p.Schema = builtinSchema(fields[2])
+			if p.Schema == nil {
+				p.Schema = &Schema{Type: SchemaType(fields[2])}
+			}
+			rest := fields[3:]
+			if len(rest) > 0 && rest[0] == "required" {
+				p.Required, rest = true, rest[1:]
+			}
+			p.Description = strings.Join(rest, " ")
+			a.params = append(a.params, p)
+		case "body":
+			a.body = value
+		case "response":
+			if len(fields) < 2 {
+				continue
+			}
+			a.responses = append(a.responses, annotatedResponse{
+				status:      fields[0],
+				typ:         fields[1],
+				description: strings.Join(fields[2:], " "),
+			})
+		}
+	}
+	sort.SliceStable(a.params, func(i, j int) bool { return a.params[i].In == "path" && a.params[j].In != "path" })
+	return a
+}
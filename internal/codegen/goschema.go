@@ -0,0 +1,516 @@
+package codegen
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/token"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/TRiZKy/gocrete/pkg/templates"
+)
+
+// schemaRef returns a reference to a component schema.
+func schemaRef(name string) *Schema {
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// typeSchema returns the schema of a Go type expression in file f. Named
+// structs of the project become component schemas.
+func (x *extractor) typeSchema(t ast.Expr, f *goFile) *Schema {
+	switch t := t.(type) {
+	case *ast.Ident:
+		if s := builtinSchema(t.Name); s != nil {
+			return s
+		}
+		if decl, ok := f.pkg.types[t.Name]; ok {
+			return x.namedSchema(decl)
+		}
+	case *ast.SelectorExpr:
+		importPath := f.importOf(t.X)
+		switch importPath + "." + t.Sel.Name {
+		case "time.Time":
+			return &Schema{Type: "string", Format: "date-time"}
+		case "time.Duration":
+			return &Schema{Type: "integer"}
+		case "github.com/google/uuid.UUID":
+			return &Schema{Type: "string", Format: "uuid"}
+		case "github.com/gin-gonic/gin.H", "github.com/gofiber/fiber/v2.Map", "github.com/labstack/echo/v4.Map":
+			return &Schema{Type: "object"}
+		}
+		if pkg, err := x.src.load(importPath); err == nil && pkg != nil {
+			if decl, ok := pkg.types[t.Sel.Name]; ok {
+				return x.namedSchema(decl)
+			}
+		}
+	case *ast.StarExpr:
+		return x.typeSchema(t.X, f)
+	case *ast.ArrayType:
+		if id, ok := t.Elt.(*ast.Ident); ok && id.Name == "byte" && t.Len == nil {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: x.typeSchema(t.Elt, f)}
+	case *ast.MapType:
+		s := &Schema{Type: "object"}
+		if value := x.typeSchema(t.Value, f); !isEmpty(value) {
+			s.AdditionalProperties = &Additional{Allowed: true, Schema: value}
+		}
+		return s
+	case *ast.StructType:
+		return x.structSchema(t, f)
+	}
+	return &Schema{}
+}
+
+func builtinSchema(name string) *Schema {
+	switch name {
+	case "string":
+		return &Schema{Type: "string"}
+	case "bool":
+		return &Schema{Type: "boolean"}
+	case "int", "int8", "int16", "int64", "uint", "uint8", "uint16", "uint32", "uint64", "byte":
+		return &Schema{Type: "integer"}
+	case "int32", "rune":
+		return &Schema{Type: "integer", Format: "int32"}
+	case "float64":
+		return &Schema{Type: "number"}
+	case "float32":
+		return &Schema{Type: "number", Format: "float"}
+	case "any", "error":
+		return &Schema{}
+	}
+	return nil
+}
+
+func isEmpty(s *Schema) bool {
+	return reflect.DeepEqual(s, &Schema{})
+}
+
+// namedSchema returns the schema of a declared type: a reference to a
+// component for structs, the schema of the underlying type otherwise.
+func (x *extractor) namedSchema(decl *typeDecl) *Schema {
+	st, ok := decl.spec.Type.(*ast.StructType)
+	if !ok {
+		s := x.typeSchema(decl.spec.Type, decl.file)
+		if s.Ref == "" {
+			s.Enum = decl.file.pkg.consts[decl.spec.Name.Name]
+			if s.Description == "" {
+				s.Description = docText(decl.doc)
+			}
+		}
+		return s
+	}
+
+	key := decl.file.pkg.path + "." + decl.spec.Name.Name
+	if name, ok := x.schemaNames[key]; ok {
+		return schemaRef(name)
+	}
+	name := decl.spec.Name.Name
+	if _, taken := x.spec.Components.Schemas[name]; taken {
+		name = templates.Pascal(decl.file.pkg.name) + name
+	}
+	x.schemaNames[key] = name
+	// Reserve the name before building, for recursive types
+	x.spec.Components.Schemas[name] = &Schema{}
+
+	s := x.structSchema(st, decl.file)
+	s.Description = docText(decl.doc)
+	x.spec.Components.Schemas[name] = s
+	return schemaRef(name)
+}
+
+// structSchema returns the object schema of a struct. Fields are named by
+// their json tags. A field is required if its validate tag says so, or if
+// it has neither a validate tag nor omitempty and is not a pointer.
+func (x *extractor) structSchema(st *ast.StructType, f *goFile) *Schema {
+	s := &Schema{Type: "object"}
+	for _, field := range st.Fields.List {
+		var tag reflect.StructTag
+		if field.Tag != nil {
+			unquoted, _ := strconv.Unquote(field.Tag.Value)
+			tag = reflect.StructTag(unquoted)
+		}
+		name, opts, _ := strings.Cut(tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+
+		if len(field.Names) == 0 {
+			// Embedded structs without a name are flattened
+			embedded := x.typeSchema(field.Type, f)
+			if name == "" {
+				if ref := strings.TrimPrefix(embedded.Ref, "#/components/schemas/"); ref != "" {
+					embedded = x.spec.Components.Schemas[ref]
+				}
+				s.Properties = append(s.Properties, embedded.Properties...)
+				s.Required = append(s.Required, embedded.Required...)
+				continue
+			}
+		}
+
+		for _, id := range field.Names {
+			if !id.IsExported() {
+				continue
+			}
+			propName := name
+			if propName == "" {
+				propName = id.Name
+			}
+			prop := x.typeSchema(field.Type, f)
+			if doc := docText(field.Doc.Text() + field.Comment.Text()); doc != "" {
+				prop.Description = doc
+			}
+			rules := tag.Get("validate")
+			applyRules(prop, rules)
+
+			_, pointer := field.Type.(*ast.StarExpr)
+			omitempty := strings.Contains(","+opts+",", ",omitempty,")
+			required := strings.Contains(","+rules+",", ",required,") ||
+				rules == "" && !omitempty && !pointer
+			// nil pointers, slices and maps are written as null
+			if !omitempty && prop.Ref == "" && prop.Format != "byte" && (pointer || prop.Type == "array" || prop.Type == "object" && isNilable(field.Type)) {
+				prop.Nullable = true
+			}
+			s.Properties = append(s.Properties, Property{Name: propName, Schema: prop})
+			if required {
+				s.Required = append(s.Required, propName)
+			}
+		}
+	}
+	return s
+}
+
+// isNilable reports whether a type expression is a map or slice.
+func isNilable(t ast.Expr) bool {
+	switch t := t.(type) {
+	case *ast.MapType:
+		return true
+	case *ast.ArrayType:
+		return t.Len == nil
+	}
+	return false
+}
+
+// applyRules adds the constraints of a validate tag to s.
+func applyRules(s *Schema, rules string) {
+	if s.Ref != "" || rules == "" {
+		return
+	}
+	for _, rule := range strings.Split(rules, ",") {
+		name, param, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		switch name {
+		case "email", "uuid":
+			s.Format = name
+		case "url":
+			s.Format = "uri"
+		case "oneof":
+			s.Enum = nil
+			for _, value := range strings.Fields(param) {
+				if s.Type == "integer" || s.Type == "number" {
+					if n, err := strconv.ParseFloat(value, 64); err == nil {
+						s.Enum = append(s.Enum, n)
+						continue
+					}
+				}
+				s.Enum = append(s.Enum, value)
+			}
+		case "min", "max", "len":
+			limit, err := strconv.ParseFloat(param, 64)
+			if err != nil {
+				continue
+			}
+			applySize(s, name, limit)
+		}
+	}
+}
+
+func applySize(s *Schema, rule string, limit float64) {
+	n := int(limit)
+	switch s.Type {
+	case "string":
+		if rule != "max" {
+			s.MinLength = &n
+		}
+		if rule != "min" {
+			s.MaxLength = &n
+		}
+	case "array":
+		if rule != "max" {
+			s.MinItems = &n
+		}
+		if rule != "min" {
+			s.MaxItems = &n
+		}
+	case "integer", "number":
+		if rule != "max" {
+			s.Minimum = &limit
+		}
+		if rule != "min" {
+			s.Maximum = &limit
+		}
+	}
+}
+
+// valueSchema returns the schema of a value written as a response, using
+// the declarations of the handler's local variables.
+func (x *extractor) valueSchema(expr ast.Expr, h *handler) *Schema {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		if e.Name == "true" || e.Name == "false" {
+			return &Schema{Type: "boolean"}
+		}
+		if local, ok := h.locals[e.Name]; ok {
+			if local.typ != nil {
+				return x.typeSchema(local.typ, h.file)
+			}
+			if _, isCall := local.value.(*ast.CallExpr); isCall {
+				break
+			}
+			if local.value != nil && local.value != expr {
+				return x.valueSchema(local.value, h)
+			}
+		}
+	case *ast.UnaryExpr:
+		if e.Op == token.AND {
+			return x.valueSchema(e.X, h)
+		}
+	case *ast.ParenExpr:
+		return x.valueSchema(e.X, h)
+	case *ast.BasicLit:
+		switch e.Kind {
+		case token.STRING:
+			return &Schema{Type: "string"}
+		case token.INT:
+			return &Schema{Type: "integer"}
+		case token.FLOAT:
+			return &Schema{Type: "number"}
+		}
+	case *ast.CompositeLit:
+		return x.literalSchema(e, e.Type, h)
+	}
+	if t, f := x.typeOf(expr, h); t != nil {
+		return x.typeSchema(t, f)
+	}
+	return &Schema{}
+}
+
+// typeOf returns the type of a value of a handler, and the file where the
+// type expression is written, when declarations tell it: the type of a
+// variable, of a field of a struct, or of a result of a function of the
+// project.
+func (x *extractor) typeOf(expr ast.Expr, h *handler) (ast.Expr, *goFile) {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		local, ok := h.locals[e.Name]
+		switch {
+		case !ok:
+		case local.typ != nil:
+			return local.typ, h.file
+		case local.value != nil && local.value != expr:
+			if call, ok := local.value.(*ast.CallExpr); ok && local.index > 0 {
+				return x.resultType(call, local.index, h.file)
+			}
+			return x.typeOf(local.value, h)
+		}
+	case *ast.UnaryExpr:
+		if e.Op == token.AND {
+			return x.typeOf(e.X, h)
+		}
+	case *ast.ParenExpr:
+		return x.typeOf(e.X, h)
+	case *ast.CompositeLit:
+		return e.Type, h.file
+	case *ast.CallExpr:
+		if fun, ok := e.Fun.(*ast.Ident); ok && (fun.Name == "make" || fun.Name == "new") && len(e.Args) > 0 {
+			return e.Args[0], h.file
+		}
+		return x.resultType(e, 0, h.file)
+	case *ast.SelectorExpr:
+		t, f := x.typeOf(e.X, h)
+		if t == nil {
+			return nil, nil
+		}
+		st, f := x.structOf(t, f)
+		if st == nil {
+			return nil, nil
+		}
+		for _, field := range st.Fields.List {
+			for _, id := range field.Names {
+				if id.Name == e.Sel.Name {
+					return field.Type, f
+				}
+			}
+		}
+	}
+	return nil, nil
+}
+
+// structOf returns the struct of a type of the project, and its file.
+func (x *extractor) structOf(t ast.Expr, f *goFile) (*ast.StructType, *goFile) {
+	var decl *typeDecl
+	switch t := t.(type) {
+	case *ast.StarExpr:
+		return x.structOf(t.X, f)
+	case *ast.StructType:
+		return t, f
+	case *ast.Ident:
+		decl = f.pkg.types[t.Name]
+	case *ast.SelectorExpr:
+		if pkg, _ := x.src.load(f.importOf(t.X)); pkg != nil {
+			decl = pkg.types[t.Sel.Name]
+		}
+	}
+	if decl == nil {
+		return nil, nil
+	}
+	return x.structOf(decl.spec.Type, decl.file)
+}
+
+// resultType returns the type of a result of a call to a function of the
+// project, and its file.
+func (x *extractor) resultType(call *ast.CallExpr, index int, f *goFile) (ast.Expr, *goFile) {
+	var fd *funcDecl
+	switch fun := call.Fun.(type) {
+	case *ast.Ident:
+		fd = f.pkg.funcs[fun.Name]
+	case *ast.SelectorExpr:
+		if importPath := f.importOf(fun.X); importPath != "" {
+			if pkg, _ := x.src.load(importPath); pkg != nil {
+				fd = pkg.funcs[fun.Sel.Name]
+			}
+		}
+	}
+	if fd == nil || fd.decl.Type.Results == nil {
+		return nil, nil
+	}
+	i := 0
+	for _, field := range fd.decl.Type.Results.List {
+		n := len(field.Names)
+		if n == 0 {
+			n = 1
+		}
+		if index < i+n {
+			return field.Type, fd.file
+		}
+		i += n
+	}
+	return nil, nil
+}
+
+// literalSchema returns the schema of a composite literal of type t. The
+// properties of map literals with string keys are inferred from their
+// elements.
+func (x *extractor) literalSchema(lit *ast.CompositeLit, t ast.Expr, h *handler) *Schema {
+	s := x.typeSchema(t, h.file)
+	switch {
+	case s.Type == "array" && len(lit.Elts) > 0:
+		if elem, ok := lit.Elts[0].(*ast.CompositeLit); ok && isEmptyObject(s.Items) {
+			elemType := elem.Type
+			if array, ok := t.(*ast.ArrayType); ok && elemType == nil {
+				elemType = array.Elt
+			}
+			if elemType != nil {
+				s.Items = x.literalSchema(elem, elemType, h)
+			}
+		}
+	case s.Type == "object" && s.AdditionalProperties == nil || isEmptyObject(s):
+		var props Properties
+		for _, elt := range lit.Elts {
+			kv, ok := elt.(*ast.KeyValueExpr)
+			if !ok {
+				return s
+			}
+			key, ok := stringLit(kv.Key)
+			if !ok {
+				return s
+			}
+			prop := x.valueSchema(kv.Value, h)
+			// a nil pointer, slice or map is written as null
+			if t, _ := x.typeOf(kv.Value, h); t != nil && prop.Ref == "" && prop.Format != "byte" && !isMade(kv.Value) {
+				if _, pointer := t.(*ast.StarExpr); pointer || isNilable(t) {
+					prop.Nullable = true
+				}
+			}
+			props = append(props, Property{Name: key, Schema: prop})
+		}
+		if len(props) > 0 {
+			s = &Schema{Type: "object", Properties: props}
+		}
+	}
+	return s
+}
+
+// isMade reports whether a value is a literal or made by make or new, and so
+// is not nil.
+func isMade(expr ast.Expr) bool {
+	switch e := expr.(type) {
+	case *ast.CompositeLit:
+		return true
+	case *ast.UnaryExpr:
+		return e.Op == token.AND
+	case *ast.ParenExpr:
+		return isMade(e.X)
+	case *ast.CallExpr:
+		fun, ok := e.Fun.(*ast.Ident)
+		return ok && (fun.Name == "make" || fun.Name == "new")
+	}
+	return false
+}
+
+// isEmptyObject reports whether s is a free-form object.
+func isEmptyObject(s *Schema) bool {
+	return s.Type == "object" && s.Ref == "" && len(s.Properties) == 0
+}
+
+// jsonSchemaOf infers the schema of a JSON document.
+func jsonSchemaOf(data []byte) (*Schema, bool) {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, false
+	}
+	return inferSchema(v), true
+}
+
+func inferSchema(v interface{}) *Schema {
+	switch v := v.(type) {
+	case string:
+		return &Schema{Type: "string"}
+	case bool:
+		return &Schema{Type: "boolean"}
+	case float64:
+		if v == float64(int64(v)) {
+			return &Schema{Type: "integer"}
+		}
+		return &Schema{Type: "number"}
+	case []interface{}:
+		s := &Schema{Type: "array", Items: &Schema{}}
+		if len(v) > 0 {
+			s.Items = inferSchema(v[0])
+		}
+		return s
+	case map[string]interface{}:
+		// encoding/json loses the order of the keys
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		s := &Schema{Type: "object"}
+		for _, key := range keys {
+			s.Properties = append(s.Properties, Property{Name: key, Schema: inferSchema(v[key])})
+		}
+		return s
+	}
+	return &Schema{}
+}
+
+// docText trims a doc comment, joining its lines into paragraphs.
+func docText(doc string) string {
+	paragraphs := strings.Split(strings.TrimSpace(doc), "\n\n")
+	for i, p := range paragraphs {
+		paragraphs[i] = strings.Join(strings.Fields(p), " ")
+	}
+	return strings.Join(paragraphs, "\n\n")
+}
@@ -0,0 +1,534 @@
+package codegen
+
+import (
+	"go/ast"
+	"go/token"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// handler is a function handling a route, found from its registration.
+type handler struct {
+	name   string
+	doc    string
+	body   *ast.BlockStmt
+	file   *goFile
+	locals map[string]local
+	// middleware are the functions the registration wraps the handler
+	// with, such as auth.RequireJWT(v) in auth.RequireJWT(v)(h).
+	middleware []ast.Expr
+}
+
+// local is a variable of a handler, declared with a type or a value. A
+// variable assigned a result of a call with several has its index.
+type local struct {
+	typ   ast.Expr
+	value ast.Expr
+	index int
+}
+
+// handlerWrappers adapt handlers between routers and net/http.
+var handlerWrappers = map[string]bool{
+	"WrapF": true, "WrapH": true, "HTTPHandlerFunc": true, "HTTPHandler": true, "HandlerFunc": true,
+	"WrapHandler": true,
+}
+
+// resolveHandler finds the function of a handler expression in f: a method
+// value, a function, a function literal, a call to a wrapper of those or to
+// middleware, or a call to a function returning a function literal.
+func (x *extractor) resolveHandler(expr ast.Expr, f *goFile) *handler {
+	switch e := expr.(type) {
+	case *ast.FuncLit:
+		return newHandler("", "", e.Type, e.Body, f)
+	case *ast.Ident:
+		if fd, ok := f.pkg.funcs[e.Name]; ok {
+			return newHandler(e.Name, fd.decl.Doc.Text(), fd.decl.Type, fd.decl.Body, fd.file)
+		}
+	case *ast.SelectorExpr:
+		if fd := x.findFunc(e, f); fd != nil {
+			return newHandler(e.Sel.Name, fd.decl.Doc.Text(), fd.decl.Type, fd.decl.Body, fd.file)
+		}
+	case *ast.CallExpr:
+		if sel, ok := e.Fun.(*ast.SelectorExpr); ok && handlerWrappers[sel.Sel.Name] && len(e.Args) == 1 {
+			return x.resolveHandler(e.Args[0], f)
+		}
+		var fd *funcDecl
+		switch fun := e.Fun.(type) {
+		case *ast.Ident:
+			fd = f.pkg.funcs[fun.Name]
+		case *ast.SelectorExpr:
+			fd = x.findFunc(fun, f)
+		}
+		if fd == nil && len(e.Args) == 1 {
+			// Middleware applied to the handler: auth.RequireJWT(v)(h)
+			return x.wrappedHandler(e, f)
+		}
+		if fd == nil || fd.decl.Body == nil {
+			return nil
+		}
+		// A constructor returning the handler
+		var lit *ast.FuncLit
+		ast.Inspect(fd.decl.Body, func(n ast.Node) bool {
+			if ret, ok := n.(*ast.ReturnStmt); ok && lit == nil && len(ret.Results) == 1 {
+				lit, _ = ret.Results[0].(*ast.FuncLit)
+			}
+			return lit == nil
+		})
+		if lit != nil {
+			return newHandler(fd.decl.Name.Name, fd.decl.Doc.Text(), lit.Type, lit.Body, fd.file)
+		}
+		if len(e.Args) == 1 {
+			// Middleware wrapping the handler: s.oidc.RequireUser(h)
+			return x.wrappedHandler(e, f)
+		}
+	}
+	return nil
+}
+
+// wrappedHandler resolves the handler of a call applying middleware to it,
+// and records the middleware.
+func (x *extractor) wrappedHandler(call *ast.CallExpr, f *goFile) *handler {
+	h := x.resolveHandler(call.Args[0], f)
+	if h != nil {
+		h.middleware = append(h.middleware, call.Fun)
+	}
+	return h
+}
+
+// middlewareStatuses returns the statuses of the problems route middleware
+// responds with, such as 401 when it authenticates, from the codes of the
+// project's errors package it uses, itself or in the functions of its
+// package it calls.
+func (x *extractor) middlewareStatuses(expr ast.Expr, f *goFile) []int {
+	if call, ok := expr.(*ast.CallExpr); ok {
+		// A middleware constructor: auth.RequireJWT(v)
+		expr = call.Fun
+	}
+	var fd *funcDecl
+	switch e := expr.(type) {
+	case *ast.Ident:
+		fd = f.pkg.funcs[e.Name]
+	case *ast.SelectorExpr:
+		fd = x.findFunc(e, f)
+	}
+
+	errorsPkg := x.src.module + "/internal/errors"
+	seen := make(map[*funcDecl]bool)
+	var statuses []int
+	var scan func(fd *funcDecl)
+	scan = func(fd *funcDecl) {
+		if fd == nil || fd.decl.Body == nil || seen[fd] {
+			return
+		}
+		seen[fd] = true
+		ast.Inspect(fd.decl.Body, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.SelectorExpr:
+				if status, ok := errorCodes[n.Sel.Name]; ok && fd.file.importOf(n.X) == errorsPkg {
+					statuses = append(statuses, status)
+				}
+			case *ast.CallExpr:
+				switch fun := n.Fun.(type) {
+				case *ast.Ident:
+					scan(fd.file.pkg.funcs[fun.Name])
+				case *ast.SelectorExpr:
+					if fds := fd.file.pkg.methods[fun.Sel.Name]; len(fds) > 0 && fd.file.importOf(fun.X) == "" {
+						scan(fds[0])
+					}
+				}
+			}
+			return true
+		})
+	}
+	scan(fd)
+	return statuses
+}
+
+// findFunc finds the function of a selector: a function of an imported
+// package of the project, or a method of that name in the package of f or
+// in a package of the project f imports.
+func (x *extractor) findFunc(sel *ast.SelectorExpr, f *goFile) *funcDecl {
+	if importPath := f.importOf(sel.X); importPath != "" {
+		if pkg, _ := x.src.load(importPath); pkg != nil {
+			return pkg.funcs[sel.Sel.Name]
+		}
+		return nil
+	}
+	if fds := f.pkg.methods[sel.Sel.Name]; len(fds) > 0 {
+		return fds[0]
+	}
+	for _, importPath := range f.imports {
+		if pkg, _ := x.src.load(importPath); pkg != nil {
+			if fds := pkg.methods[sel.Sel.Name]; len(fds) > 0 {
+				return fds[0]
+			}
+		}
+	}
+	return nil
+}
+
+func newHandler(name, doc string, typ *ast.FuncType, body *ast.BlockStmt, f *goFile) *handler {
+	h := &handler{name: name, doc: doc, body: body, file: f, locals: make(map[string]local)}
+	for _, field := range typ.Params.List {
+		for _, id := range field.Names {
+			h.locals[id.Name] = local{typ: field.Type}
+		}
+	}
+	if body == nil {
+		return h
+	}
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.ValueSpec:
+			for i, id := range n.Names {
+				l := local{typ: n.Type}
+				if len(n.Values) == len(n.Names) {
+					l.value = n.Values[i]
+				}
+				h.locals[id.Name] = l
+			}
+		case *ast.AssignStmt:
+			if n.Tok != token.DEFINE {
+				return true
+			}
+			for i, lhs := range n.Lhs {
+				id, ok := lhs.(*ast.Ident)
+				switch {
+				case !ok || id.Name == "_":
+				case len(n.Lhs) == len(n.Rhs):
+					h.locals[id.Name] = local{value: n.Rhs[i]}
+				case len(n.Rhs) == 1:
+					// user, err := store.Get(ctx, id)
+					h.locals[id.Name] = local{value: n.Rhs[0], index: i}
+				}
+			}
+		}
+		return true
+	})
+	return h
+}
+
+// analysis is what a handler's code tells about its operation.
+type analysis struct {
+	body      *Schema
+	strict    bool
+	params    []*Parameter
+	responses map[string]*Response
+	problems  bool
+}
+
+// statusCodes maps the net/http status constants, also defined by fiber,
+// to their codes.
+var statusCodes = map[string]int{
+	"StatusOK": 200, "StatusCreated": 201, "StatusAccepted": 202, "StatusNoContent": 204,
+	"StatusMovedPermanently": 301, "StatusFound": 302, "StatusSeeOther": 303,
+	"StatusNotModified": 304, "StatusTemporaryRedirect": 307, "StatusPermanentRedirect": 308,
+	"StatusBadRequest": 400, "StatusUnauthorized": 401, "StatusForbidden": 403,
+	"StatusNotFound": 404, "StatusMethodNotAllowed": 405, "StatusConflict": 409,
+	"StatusGone": 410, "StatusRequestEntityTooLarge": 413, "StatusUnsupportedMediaType": 415,
+	"StatusUnprocessableEntity": 422, "StatusTooManyRequests": 429,
+	"StatusInternalServerError": 500, "StatusNotImplemented": 501, "StatusBadGateway": 502,
+	"StatusServiceUnavailable": 503, "StatusGatewayTimeout": 504,
+}
+
+// errorCodes maps the codes of the project's errors package to their
+// statuses.
+var errorCodes = map[string]int{
+	"CodeInvalidArgument": 400, "CodeValidation": 422, "CodeUnauthenticated": 401,
+	"CodePermissionDenied": 403, "CodeNotFound": 404, "CodeMethodNotAllowed": 405,
+	"CodeConflict": 409, "CodeTooLarge": 413, "CodeUnsupportedMedia": 415,
+	"CodeRateLimited": 429, "CodeInternal": 500, "CodeUnavailable": 503,
+}
+
+// ignoredHeaders may not be described as parameters.
+var ignoredHeaders = map[string]bool{"Accept": true, "Content-Type": true, "Authorization": true}
+
+// analyze finds the request body, parameters and responses of a handler.
+// Responses are found in source order: a status written by WriteHeader or
+// Status applies to the body written next, if any.
+func (x *extractor) analyze(h *handler) *analysis {
+	a := &analysis{responses: make(map[string]*Response)}
+	if h.body == nil {
+		return a
+	}
+	errorsPkg := x.src.module + "/internal/errors"
+	validatePkg := x.src.module + "/internal/validate"
+
+	pending := 0
+	flush := func() {
+		if pending != 0 {
+			a.respond(pending, nil)
+			pending = 0
+		}
+	}
+	write := func(status int, schema *Schema) {
+		if status == 0 {
+			status = pending
+		}
+		if status == 0 {
+			status = http.StatusOK
+		}
+		a.respond(status, schema)
+		pending = 0
+	}
+	done := make(map[*ast.CallExpr]bool)
+
+	ast.Inspect(h.body, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			switch h.file.importOf(sel.X) {
+			case errorsPkg:
+				a.problems = true
+				if status, ok := errorCodes[sel.Sel.Name]; ok {
+					a.problem(status)
+				}
+			case validatePkg:
+				a.problems = true
+			}
+		}
+		call, ok := n.(*ast.CallExpr)
+		if !ok || done[call] {
+			return true
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		if x.param(a, call, h, nil) {
+			return true
+		}
+		pkg := h.file.importOf(sel.X)
+		name := sel.Sel.Name
+
+		switch {
+		// Request bodies
+		case pkg == validatePkg && (name == "Bind" && len(call.Args) == 3 || name == "BindJSON" && len(call.Args) == 2):
+			a.body = x.valueSchema(call.Args[len(call.Args)-1], h)
+			a.strict = true
+			a.problem(http.StatusBadRequest)
+			a.problem(http.StatusUnprocessableEntity)
+		case name == "Decode" && len(call.Args) == 1 && isCall(sel.X, "NewDecoder"),
+			pkg == "" && len(call.Args) == 1 && (name == "ShouldBindJSON" || name == "BindJSON" || name == "BodyParser" || name == "Bind"):
+			a.body = x.valueSchema(call.Args[0], h)
+
+			// Parameters
+		case pkg == "strconv" && len(call.Args) > 0:
+			if arg, ok := call.Args[0].(*ast.CallExpr); ok && x.param(a, arg, h, &Schema{Type: "integer"}) {
+				done[arg] = true
+			}
+
+		// Responses
+		case name == "WriteHeader" && len(call.Args) == 1:
+			flush()
+			pending = status(call.Args[0])
+		case name == "Encode" && len(call.Args) == 1 && isCall(sel.X, "NewEncoder"):
+			write(0, x.valueSchema(call.Args[0], h))
+		case name == "Write" && len(call.Args) == 1 && pkg == "":
+			write(0, writtenSchema(call.Args[0]))
+		case pkg == "" && len(call.Args) == 2 && (name == "JSON" || name == "IndentedJSON" || name == "PureJSON" || name == "AbortWithStatusJSON"):
+			// gin and echo
+			write(status(call.Args[0]), x.valueSchema(call.Args[1], h))
+		case pkg == "" && name == "JSON" && len(call.Args) == 1:
+			// fiber, maybe c.Status(code).JSON(v)
+			code := 0
+			if inner, ok := sel.X.(*ast.CallExpr); ok && isCall(inner, "Status") && len(inner.Args) == 1 {
+				code = status(inner.Args[0])
+				done[inner] = true
+			}
+			write(code, x.valueSchema(call.Args[0], h))
+		case pkg == "" && len(call.Args) == 1 && (name == "Status" || name == "AbortWithStatus" || name == "SendStatus" || name == "NoContent"):
+			flush()
+			pending = status(call.Args[0])
+		case pkg == "net/http" && name == "Error" && len(call.Args) == 3:
+			write(status(call.Args[2]), nil)
+		case pkg == "net/http" && name == "Redirect" && len(call.Args) == 4:
+			flush()
+			a.redirect(status(call.Args[3]))
+		case pkg == "" && name == "Redirect" && (len(call.Args) == 1 || len(call.Args) == 2):
+			// gin and echo take the status first, fiber after the URL,
+			// defaulting to 302
+			code := status(call.Args[0])
+			if code == 0 && len(call.Args) == 2 {
+				code = status(call.Args[1])
+			}
+			flush()
+			a.redirect(code)
+		}
+		return true
+	})
+	flush()
+	return a
+}
+
+// param records a path, query or header parameter read by call, and
+// reports whether it is one. Its schema defaults to the type of the
+// accessor.
+func (x *extractor) param(a *analysis, call *ast.CallExpr, h *handler, schema *Schema) bool {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || len(call.Args) == 0 {
+		return false
+	}
+	nameArg := call.Args[0]
+	in := ""
+	typ := "string"
+	switch name := sel.Sel.Name; {
+	case name == "URLParam" && len(call.Args) == 2:
+		// chi.URLParam(r, "id")
+		in, nameArg = "path", call.Args[1]
+	case name == "PathValue" || name == "Param" || name == "Params":
+		in = "path"
+	case name == "ParamsInt":
+		in, typ = "path", "integer"
+	case name == "Get" && isCall(sel.X, "Query"):
+		// r.URL.Query().Get("q")
+		in = "query"
+	case name == "Query" || name == "DefaultQuery" || name == "GetQuery" || name == "QueryParam":
+		in = "query"
+	case name == "QueryInt":
+		in, typ = "query", "integer"
+	case name == "QueryFloat":
+		in, typ = "query", "number"
+	case name == "QueryBool":
+		in, typ = "query", "boolean"
+	case name == "QueryArray":
+		in, typ = "query", "array"
+	case name == "Get" && isSelector(sel.X, "Header"), name == "GetHeader",
+		name == "Get" && isFiberCtx(sel.X, h):
+		in = "header"
+	default:
+		return false
+	}
+	wire, ok := stringLit(nameArg)
+	if !ok || in == "header" && ignoredHeaders[http.CanonicalHeaderKey(wire)] {
+		return false
+	}
+
+	if schema == nil {
+		schema = &Schema{Type: SchemaType(typ)}
+		if typ == "array" {
+			schema.Items = &Schema{Type: "string"}
+		}
+	}
+	for _, p := range a.params {
+		if p.Name == wire && p.In == in {
+			return true
+		}
+	}
+	a.params = append(a.params, &Parameter{Name: wire, In: in, Required: in == "path", Schema: schema})
+	return true
+}
+
+// respond records a response, keeping the first one of each status.
+func (a *analysis) respond(status int, schema *Schema) {
+	key := strconv.Itoa(status)
+	if existing, ok := a.responses[key]; ok && (existing.Ref == "" && len(existing.Content) > 0 || schema == nil) {
+		return
+	}
+	resp := &Response{Description: http.StatusText(status)}
+	if schema != nil && status != http.StatusNoContent {
+		resp.Content = map[string]*MediaType{"application/json": {Schema: schema}}
+	}
+	a.responses[key] = resp
+}
+
+// redirect records a redirect response, with its Location header. The
+// status defaults to 302.
+func (a *analysis) redirect(status int) {
+	if status == 0 {
+		status = http.StatusFound
+	}
+	a.respond(status, nil)
+	if resp := a.responses[strconv.Itoa(status)]; resp.Ref == "" {
+		resp.Headers = map[string]*Header{
+			"Location": {Description: "Where the client is redirected", Schema: &Schema{Type: "string"}},
+		}
+	}
+}
+
+// problem records a problem details response.
+func (a *analysis) problem(status int) {
+	key := strconv.Itoa(status)
+	if _, ok := a.responses[key]; !ok {
+		a.responses[key] = &Response{Ref: problemRef}
+	}
+}
+
+// status returns the value of a status code expression, or 0.
+func status(expr ast.Expr) int {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		if n, err := strconv.Atoi(e.Value); err == nil {
+			return n
+		}
+	case *ast.SelectorExpr:
+		return statusCodes[e.Sel.Name]
+	}
+	return 0
+}
+
+// writtenSchema returns the schema of bytes written by a handler: that of
+// a JSON literal, or nil.
+func writtenSchema(expr ast.Expr) *Schema {
+	conv, ok := expr.(*ast.CallExpr)
+	if !ok || len(conv.Args) != 1 {
+		return nil
+	}
+	if s, ok := stringLit(conv.Args[0]); ok {
+		if schema, ok := jsonSchemaOf([]byte(s)); ok {
+			return schema
+		}
+	}
+	return nil
+}
+
+// isCall reports whether expr calls a function or method named name.
+func isCall(expr ast.Expr, name string) bool {
+	call, ok := expr.(*ast.CallExpr)
+	if !ok {
+		return false
+	}
+	switch fun := call.Fun.(type) {
+	case *ast.SelectorExpr:
+		return fun.Sel.Name == name
+	case *ast.Ident:
+		return fun.Name == name
+	}
+	return false
+}
+
+// isFiberCtx reports whether expr is a *fiber.Ctx parameter of h.
+func isFiberCtx(expr ast.Expr, h *handler) bool {
+	id, ok := expr.(*ast.Ident)
+	if !ok {
+		return false
+	}
+	star, ok := h.locals[id.Name].typ.(*ast.StarExpr)
+	if !ok {
+		return false
+	}
+	sel, ok := star.X.(*ast.SelectorExpr)
+	return ok && sel.Sel.Name == "Ctx" && h.file.importOf(sel.X) == "github.com/gofiber/fiber/v2"
+}
+
+// isSelector reports whether expr selects a field named name.
+func isSelector(expr ast.Expr, name string) bool {
+	sel, ok := expr.(*ast.SelectorExpr)
+	return ok && sel.Sel.Name == name
+}
+
+// operationSummary splits a handler's doc comment into a summary, its
+// first sentence, and a description. A leading handler name is dropped:
+// "ListUsers lists users." is summarized as "Lists users".
+func operationSummary(name, doc string) (string, string) {
+	text := docText(doc)
+	if rest, ok := strings.CutPrefix(text, name+" "); ok && name != "" {
+		text = strings.ToUpper(rest[:1]) + rest[1:]
+	}
+	first, rest, _ := strings.Cut(text, "\n\n")
+	summary, more, _ := strings.Cut(first, ". ")
+	description := strings.TrimSpace(more)
+	if rest != "" {
+		description = strings.TrimSpace(description + "\n\n" + rest)
+	}
+	return strings.TrimSuffix(summary, "."), description
+}
@@ -0,0 +1,726 @@
+package codegen
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/TRiZKy/gocrete/pkg/templates"
+)
+
+// API is the model of a generated client package.
+type API struct {
+	Package   string
+	Title     string
+	Version   string
+	Types     []*Type
+	Endpoints []*Endpoint
+}
+
+// TypeImports returns the packages used by the types.
+func (a *API) TypeImports() []string {
+	var imports []string
+	for _, pkg := range []string{"encoding/json", "time"} {
+		prefix := strings.TrimPrefix(pkg, "encoding/") + "."
+		for _, t := range a.Types {
+			if t.uses(prefix) {
+				imports = append(imports, pkg)
+				break
+			}
+		}
+	}
+	return imports
+}
+
+// EndpointImports returns the packages used by the endpoints, besides
+// context and net/http.
+func (a *API) EndpointImports() []string {
+	var path, query, header bool
+	for _, op := range a.Endpoints {
+		path = path || len(op.PathParams) > 0
+		for _, p := range op.Params {
+			query = query || p.In == "query"
+			header = header || p.In == "header"
+		}
+	}
+	var imports []string
+	if path || query || header {
+		imports = append(imports, "fmt")
+	}
+	if path || query {
+		imports = append(imports, "net/url")
+	}
+	return imports
+}
+
+// Type is a named Go type for a schema.
+type Type struct {
+	Name string
+	Doc  string
+	// Fields are set for structs; other types are defined by Underlying.
+	Fields     []*Field
+	Struct     bool
+	Underlying string
+	Enum       []EnumValue
+}
+
+func (t *Type) uses(prefix string) bool {
+	if strings.Contains(t.Underlying, prefix) {
+		return true
+	}
+	for _, f := range t.Fields {
+		if strings.Contains(f.Type, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// EnumValue is a constant of an enum type.
+type EnumValue struct {
+	Name  string
+	Value string
+}
+
+// Field is a field of a struct type.
+type Field struct {
+	Name string
+	JSON string
+	Type string
+	Doc  string
+}
+
+// Endpoint is an API operation, a method of the client.
+type Endpoint struct {
+	Name   string
+	Method string
+	Path   string
+	Doc    string
+	// PathExpr builds the request path from the path parameters.
+	PathExpr string
+	// Pattern routes the operation in an http.ServeMux.
+	Pattern    string
+	PathParams []*Param
+	// Params are the query and header parameters, fields of ParamsType.
+	Params     []*Param
+	ParamsType string
+	Body       string
+	Result     string
+	// ResultPointer is set when the result is returned as a pointer.
+	ResultPointer bool
+	Status        int
+}
+
+// Signature returns the parameters of the endpoint's method.
+func (e *Endpoint) Signature() string {
+	params := []string{"ctx context.Context"}
+	for _, p := range e.PathParams {
+		params = append(params, p.Arg+" "+p.Type)
+	}
+	if e.Body != "" {
+		params = append(params, "body "+e.Body)
+	}
+	if e.ParamsType != "" {
+		params = append(params, "params *"+e.ParamsType)
+	}
+	return strings.Join(params, ", ")
+}
+
+// Args returns the arguments of a call of the endpoint's method, with the
+// same names as in Signature.
+func (e *Endpoint) Args() string {
+	return strings.Join(append([]string{"ctx"}, e.inputs()...), ", ")
+}
+
+// Inputs returns the arguments of Args but the context.
+func (e *Endpoint) Inputs() string {
+	return strings.Join(e.inputs(), ", ")
+}
+
+func (e *Endpoint) inputs() []string {
+	var args []string
+	for _, p := range e.PathParams {
+		args = append(args, p.Arg)
+	}
+	if e.Body != "" {
+		args = append(args, "body")
+	}
+	if e.ParamsType != "" {
+		args = append(args, "params")
+	}
+	return args
+}
+
+// Returns returns the results of the endpoint's method.
+func (e *Endpoint) Returns() string {
+	switch {
+	case e.Result == "":
+		return "error"
+	case e.ResultPointer:
+		return "(*" + e.Result + ", error)"
+	default:
+		return "(" + e.Result + ", error)"
+	}
+}
+
+// Param is a path, query or header parameter.
+type Param struct {
+	// Name is the field name in the params struct, Arg the argument name for
+	// path parameters and Wire the name in the spec.
+	Name     string
+	Arg      string
+	Wire     string
+	In       string
+	Type     string
+	Elem     string
+	Required bool
+	Doc      string
+}
+
+// Slice reports whether the parameter is a list.
+func (p *Param) Slice() bool {
+	return p.Elem != ""
+}
+
+// Pointer reports whether the parameter is optional and passed as a
+// pointer.
+func (p *Param) Pointer() bool {
+	return !p.Required && !p.Slice()
+}
+
+// Sample returns a Go literal of the parameter's type, for tests.
+func (p *Param) Sample() string {
+	if p.Slice() {
+		return p.Type + "{" + sample(p.Elem) + "}"
+	}
+	return sample(p.Type)
+}
+
+func sample(goType string) string {
+	switch goType {
+	case "string":
+		return `"a"`
+	case "bool":
+		return "true"
+	case "float32", "float64":
+		return "1.5"
+	default:
+		return "1"
+	}
+}
+
+// reserved are the names declared by the client templates.
+var reserved = []string{
+	"API", "Client", "Error", "FieldError", "New", "NewHandler", "Option",
+	"RequestEditor", "WithBearerToken", "WithHeader", "WithHTTPClient",
+	"WithRequestEditor", "WithRetries",
+}
+
+// reservedArgs are the variable names used by the generated methods and
+// handlers, which path parameters must not shadow.
+var reservedArgs = map[string]bool{
+	"api": true, "body": true, "c": true, "ctx": true, "err": true, "header": true,
+	"params": true, "query": true, "r": true, "req": true, "result": true, "w": true,
+}
+
+// Build builds the model of a client package for spec.
+func Build(spec *Spec, pkg string) (*API, error) {
+	b := &builder{spec: spec, names: make(map[string]bool), schemas: make(map[string]string)}
+	for _, name := range reserved {
+		b.names[name] = true
+	}
+	api := &API{Package: pkg, Title: spec.Info.Title, Version: spec.Info.Version}
+
+	// Name component schemas first, so that they keep their names
+	schemaNames := make([]string, 0, len(spec.Components.Schemas))
+	for name := range spec.Components.Schemas {
+		schemaNames = append(schemaNames, name)
+	}
+	sort.Strings(schemaNames)
+	for _, name := range schemaNames {
+		b.schemas[name] = b.unique(templates.Pascal(name))
+	}
+	for _, name := range schemaNames {
+		if err := b.namedType(b.schemas[name], spec.Components.Schemas[name]); err != nil {
+			return nil, fmt.Errorf("schema %s: %w", name, err)
+		}
+	}
+
+	paths := make([]string, 0, len(spec.Paths))
+	for path := range spec.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		item := spec.Paths[path]
+		for _, mo := range item.Operations() {
+			op, err := b.operation(path, mo.Method, item, mo.Operation)
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %w", mo.Method, path, err)
+			}
+			api.Endpoints = append(api.Endpoints, op)
+		}
+	}
+
+	api.Types = b.types
+	return api, nil
+}
+
+type builder struct {
+	spec  *Spec
+	types []*Type
+	names map[string]bool
+	// schemas maps component schemas to their Go names.
+	schemas map[string]string
+}
+
+// unique returns name, or name with a number if it is taken.
+func (b *builder) unique(name string) string {
+	candidate := name
+	for i := 2; b.names[candidate]; i++ {
+		candidate = name + strconv.Itoa(i)
+	}
+	b.names[candidate] = true
+	return candidate
+}
+
+func (b *builder) operation(path, method string, item *PathItem, o *Operation) (*Endpoint, error) {
+	name := o.OperationID
+	if name == "" {
+		name = strings.ToLower(method) + " " + strings.NewReplacer("{", "by ", "}", "").Replace(path)
+	}
+	op := &Endpoint{
+		Name:   b.unique(templates.Pascal(name)),
+		Method: method,
+		Path:   path,
+		Doc:    firstNonEmpty(o.Summary, o.Description),
+	}
+
+	params, err := b.parameters(item.Parameters, o.Parameters)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range params {
+		if p.In == "path" {
+			op.PathParams = append(op.PathParams, p)
+		} else {
+			op.Params = append(op.Params, p)
+		}
+	}
+	if len(op.Params) > 0 {
+		op.ParamsType = b.unique(op.Name + "Params")
+	}
+	if op.PathExpr, op.Pattern, err = pathExpr(method, path, op.PathParams); err != nil {
+		return nil, err
+	}
+
+	if o.RequestBody != nil {
+		body, err := b.requestBody(o.RequestBody)
+		if err != nil {
+			return nil, err
+		}
+		if schema, err := jsonSchema(body.Content); err != nil {
+			return nil, fmt.Errorf("request body: %w", err)
+		} else if op.Body, err = b.goType(schema, op.Name+"Request"); err != nil {
+			return nil, fmt.Errorf("request body: %w", err)
+		}
+	}
+
+	if err := b.result(op, o.Responses); err != nil {
+		return nil, err
+	}
+	return op, nil
+}
+
+// result sets the status and result type of the first success response.
+func (b *builder) result(op *Endpoint, responses map[string]*Response) error {
+	codes := make([]string, 0, len(responses))
+	for code := range responses {
+		if strings.HasPrefix(code, "2") {
+			codes = append(codes, code)
+		}
+	}
+	sort.Strings(codes)
+	op.Status = 200
+	if len(codes) == 0 {
+		return nil
+	}
+	if status, err := strconv.Atoi(codes[0]); err == nil {
+		op.Status = status
+	}
+
+	resp, err := b.response(responses[codes[0]])
+	if err != nil {
+		return err
+	}
+	// Responses to HEAD requests have no body
+	if len(resp.Content) == 0 || op.Status == 204 || op.Method == "HEAD" {
+		return nil
+	}
+	schema, err := jsonSchema(resp.Content)
+	if err != nil {
+		return fmt.Errorf("response %s: %w", codes[0], err)
+	}
+	if op.Result, err = b.goType(schema, op.Name+"Response"); err != nil {
+		return fmt.Errorf("response %s: %w", codes[0], err)
+	}
+	op.ResultPointer = !strings.HasPrefix(op.Result, "[]") && !strings.HasPrefix(op.Result, "map[") && op.Result != "interface{}"
+	return nil
+}
+
+// parameters merges the parameters of a path and of its operation, which
+// override them. Cookie parameters are not supported and skipped.
+func (b *builder) parameters(pathParams, opParams []*Parameter) ([]*Param, error) {
+	var merged []*Parameter
+	index := make(map[string]int)
+	for _, p := range append(append([]*Parameter{}, pathParams...), opParams...) {
+		p, err := b.parameter(p)
+		if err != nil {
+			return nil, err
+		}
+		key := p.In + ":" + p.Name
+		if i, ok := index[key]; ok {
+			merged[i] = p
+			continue
+		}
+		index[key] = len(merged)
+		merged = append(merged, p)
+	}
+
+	var params []*Param
+	names := make(map[string]bool)
+	for _, p := range merged {
+		if p.In == "cookie" {
+			continue
+		}
+		param := &Param{
+			Name:     templates.Pascal(p.Name),
+			Arg:      templates.GoIdent(p.Name),
+			Wire:     p.Name,
+			In:       p.In,
+			Required: p.Required || p.In == "path",
+			Doc:      p.Description,
+		}
+		for names[param.Name] || reservedArgs[param.Arg] {
+			param.Name += "_"
+			param.Arg += "_"
+		}
+		names[param.Name] = true
+
+		var err error
+		if param.Type, param.Elem, err = b.paramType(p.Schema); err != nil {
+			return nil, fmt.Errorf("parameter %s: %w", p.Name, err)
+		}
+		if param.In == "path" && param.Slice() {
+			return nil, fmt.Errorf("path parameter %s: only scalars are supported", p.Name)
+		}
+		params = append(params, param)
+	}
+	return params, nil
+}
+
+func (b *builder) parameter(p *Parameter) (*Parameter, error) {
+	if p.Ref == "" {
+		return p, nil
+	}
+	name, err := refName(p.Ref, "parameters")
+	if err != nil {
+		return nil, err
+	}
+	if resolved, ok := b.spec.Components.Parameters[name]; ok {
+		return b.parameter(resolved)
+	}
+	return nil, fmt.Errorf("unknown parameter %q", p.Ref)
+}
+
+func (b *builder) requestBody(r *RequestBody) (*RequestBody, error) {
+	if r.Ref == "" {
+		return r, nil
+	}
+	name, err := refName(r.Ref, "requestBodies")
+	if err != nil {
+		return nil, err
+	}
+	if resolved, ok := b.spec.Components.RequestBodies[name]; ok {
+		return b.requestBody(resolved)
+	}
+	return nil, fmt.Errorf("unknown request body %q", r.Ref)
+}
+
+func (b *builder) response(r *Response) (*Response, error) {
+	if r == nil || r.Ref == "" {
+		return r, nil
+	}
+	name, err := refName(r.Ref, "responses")
+	if err != nil {
+		return nil, err
+	}
+	if resolved, ok := b.spec.Components.Responses[name]; ok {
+		return b.response(resolved)
+	}
+	return nil, fmt.Errorf("unknown response %q", r.Ref)
+}
+
+func (b *builder) schema(s *Schema) (*Schema, error) {
+	if s == nil || s.Ref == "" {
+		return s, nil
+	}
+	name, err := refName(s.Ref, "schemas")
+	if err != nil {
+		return nil, err
+	}
+	if resolved, ok := b.spec.Components.Schemas[name]; ok {
+		return b.schema(resolved)
+	}
+	return nil, fmt.Errorf("unknown schema %q", s.Ref)
+}
+
+// jsonSchema returns the schema of the JSON content of a body.
+func jsonSchema(content map[string]*MediaType) (*Schema, error) {
+	types := make([]string, 0, len(content))
+	for mediaType := range content {
+		types = append(types, mediaType)
+	}
+	sort.Strings(types)
+	for _, mediaType := range types {
+		if mediaType == "application/json" || strings.HasSuffix(mediaType, "+json") {
+			return content[mediaType].Schema, nil
+		}
+	}
+	return nil, fmt.Errorf("only JSON content is supported, not %s", strings.Join(types, ", "))
+}
+
+// namedType defines a type for a component schema.
+func (b *builder) namedType(name string, s *Schema) error {
+	if isStruct(s) {
+		return b.structType(name, s)
+	}
+	underlying, err := b.goType(s, name)
+	if err != nil {
+		return err
+	}
+	t := &Type{Name: name, Doc: s.Description, Underlying: underlying}
+	if underlying == "string" {
+		for _, v := range s.Enum {
+			value := fmt.Sprint(v)
+			t.Enum = append(t.Enum, EnumValue{Name: b.unique(name + templates.Pascal(value)), Value: strconv.Quote(value)})
+		}
+	}
+	b.types = append(b.types, t)
+	return nil
+}
+
+func isStruct(s *Schema) bool {
+	return s.Ref == "" && (len(s.Properties) > 0 || len(s.AllOf) > 0)
+}
+
+// structType defines a struct for an object schema. The properties of allOf
+// schemas are merged.
+func (b *builder) structType(name string, s *Schema) error {
+	t := &Type{Name: name, Doc: s.Description, Struct: true}
+	b.types = append(b.types, t)
+
+	props, required, err := b.properties(s)
+	if err != nil {
+		return err
+	}
+	names := make(map[string]bool)
+	for _, prop := range props {
+		field := &Field{Name: templates.Pascal(prop.Name), Doc: prop.Schema.Description}
+		for names[field.Name] {
+			field.Name += "_"
+		}
+		names[field.Name] = true
+
+		if field.Type, err = b.goType(prop.Schema, name+field.Name); err != nil {
+			return fmt.Errorf("property %s: %w", prop.Name, err)
+		}
+		field.JSON = prop.Name
+		if !required[prop.Name] {
+			field.JSON += ",omitempty"
+			if !nillable(field.Type) {
+				field.Type = "*" + field.Type
+			}
+		}
+		t.Fields = append(t.Fields, field)
+	}
+	return nil
+}
+
+func (b *builder) properties(s *Schema) (Properties, map[string]bool, error) {
+	s, err := b.schema(s)
+	if err != nil {
+		return nil, nil, err
+	}
+	props := append(Properties{}, s.Properties...)
+	required := make(map[string]bool)
+	for _, name := range s.Required {
+		required[name] = true
+	}
+	for _, sub := range s.AllOf {
+		subProps, subRequired, err := b.properties(sub)
+		if err != nil {
+			return nil, nil, err
+		}
+		props = append(props, subProps...)
+		for name := range subRequired {
+			required[name] = true
+		}
+	}
+	return props, required, nil
+}
+
+func nillable(goType string) bool {
+	return strings.HasPrefix(goType, "[]") || strings.HasPrefix(goType, "map[") ||
+		goType == "interface{}" || goType == "json.RawMessage"
+}
+
+// goType returns the Go type of a schema, defining a type named hint for
+// inline object schemas.
+func (b *builder) goType(s *Schema, hint string) (string, error) {
+	if s == nil {
+		return "interface{}", nil
+	}
+	if s.Ref != "" {
+		name, err := refName(s.Ref, "schemas")
+		if err != nil {
+			return "", err
+		}
+		if goName, ok := b.schemas[name]; ok {
+			return goName, nil
+		}
+		return "", fmt.Errorf("unknown schema %q", s.Ref)
+	}
+	if isStruct(s) {
+		name := b.unique(hint)
+		return name, b.structType(name, s)
+	}
+	if len(s.OneOf) > 0 || len(s.AnyOf) > 0 {
+		return "json.RawMessage", nil
+	}
+
+	switch s.Type {
+	case "string":
+		switch s.Format {
+		case "date-time":
+			return "time.Time", nil
+		case "byte":
+			return "[]byte", nil
+		}
+		return "string", nil
+	case "integer":
+		if s.Format == "int32" {
+			return "int32", nil
+		}
+		return "int64", nil
+	case "number":
+		if s.Format == "float" {
+			return "float32", nil
+		}
+		return "float64", nil
+	case "boolean":
+		return "bool", nil
+	case "array":
+		elem, err := b.goType(s.Items, hint+"Item")
+		return "[]" + elem, err
+	case "object", "":
+		if s.AdditionalProperties != nil && s.AdditionalProperties.Schema != nil {
+			elem, err := b.goType(s.AdditionalProperties.Schema, hint+"Value")
+			return "map[string]" + elem, err
+		}
+		if s.Type == "object" {
+			return "map[string]interface{}", nil
+		}
+		return "interface{}", nil
+	default:
+		return "", fmt.Errorf("unsupported type %q", s.Type)
+	}
+}
+
+// paramType returns the Go type of a parameter, and of its elements for
+// lists. Parameters must be scalars or lists of scalars.
+func (b *builder) paramType(s *Schema) (string, string, error) {
+	s, err := b.schema(s)
+	if err != nil {
+		return "", "", err
+	}
+	if s == nil {
+		return "string", "", nil
+	}
+	if s.Type == "array" {
+		elem, _, err := b.paramType(s.Items)
+		if err != nil || strings.HasPrefix(elem, "[]") {
+			return "", "", fmt.Errorf("only lists of scalars are supported")
+		}
+		return "[]" + elem, elem, nil
+	}
+	switch s.Type {
+	case "string", "":
+		return "string", "", nil
+	case "integer":
+		if s.Format == "int32" {
+			return "int32", "", nil
+		}
+		return "int64", "", nil
+	case "number":
+		if s.Format == "float" {
+			return "float32", "", nil
+		}
+		return "float64", "", nil
+	case "boolean":
+		return "bool", "", nil
+	default:
+		return "", "", fmt.Errorf("unsupported parameter type %q", s.Type)
+	}
+}
+
+// pathExpr returns the Go expression building a path from its parameters,
+// and the http.ServeMux pattern matching it.
+func pathExpr(method, path string, params []*Param) (string, string, error) {
+	byWire := make(map[string]*Param)
+	for _, p := range params {
+		byWire[p.Wire] = p
+	}
+
+	var parts []string
+	pattern := path
+	rest := path
+	for {
+		start := strings.Index(rest, "{")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(rest[start:], "}")
+		if end < 0 {
+			return "", "", fmt.Errorf("unterminated parameter in path %q", path)
+		}
+		wire := rest[start+1 : start+end]
+		p, ok := byWire[wire]
+		if !ok {
+			return "", "", fmt.Errorf("path parameter %q is not defined", wire)
+		}
+		if literal := rest[:start]; literal != "" {
+			parts = append(parts, strconv.Quote(literal))
+		}
+		parts = append(parts, "url.PathEscape(fmt.Sprint("+p.Arg+"))")
+		pattern = strings.Replace(pattern, "{"+wire+"}", "{"+p.Name+"}", 1)
+		rest = rest[start+end+1:]
+	}
+	if rest != "" {
+		parts = append(parts, strconv.Quote(rest))
+	}
+	if strings.HasSuffix(pattern, "/") {
+		pattern += "{$}"
+	}
+	return strings.Join(parts, " + "), method + " " + pattern, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
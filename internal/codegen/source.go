@@ -0,0 +1,246 @@
+package codegen
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// source is the parsed Go code of a project's packages, loaded on demand.
+type source struct {
+	fsys   fs.FS
+	module string
+	fset   *token.FileSet
+	pkgs   map[string]*goPackage
+}
+
+// goPackage holds the declarations of a package.
+type goPackage struct {
+	path    string
+	name    string
+	files   []*goFile
+	types   map[string]*typeDecl
+	funcs   map[string]*funcDecl
+	methods map[string][]*funcDecl
+	// consts are the constant values declared for each named type.
+	consts map[string][]interface{}
+}
+
+// goFile is a parsed file and the imports that resolve its selectors.
+type goFile struct {
+	pkg     *goPackage
+	ast     *ast.File
+	imports map[string]string
+}
+
+type typeDecl struct {
+	spec *ast.TypeSpec
+	doc  string
+	file *goFile
+}
+
+type funcDecl struct {
+	decl *ast.FuncDecl
+	file *goFile
+}
+
+func newSource(fsys fs.FS, module string) *source {
+	return &source{fsys: fsys, module: module, fset: token.NewFileSet(), pkgs: make(map[string]*goPackage)}
+}
+
+// modulePath returns the module path of the go.mod of fsys.
+func modulePath(fsys fs.FS) (string, error) {
+	goMod, err := fs.ReadFile(fsys, "go.mod")
+	if err != nil {
+		return "", fmt.Errorf("failed to read go.mod: %w", err)
+	}
+	for _, line := range strings.Split(string(goMod), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "module ") {
+			return strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "module ")), nil
+		}
+	}
+	return "", fmt.Errorf("module path not found in go.mod")
+}
+
+// load returns a package of the project by import path, or nil for other
+// packages.
+func (s *source) load(importPath string) (*goPackage, error) {
+	if pkg, ok := s.pkgs[importPath]; ok {
+		return pkg, nil
+	}
+	rel, ok := strings.CutPrefix(importPath, s.module)
+	if !ok || rel != "" && !strings.HasPrefix(rel, "/") {
+		return nil, nil
+	}
+	dir := path.Join(".", strings.TrimPrefix(rel, "/"))
+	entries, err := fs.ReadDir(s.fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read package %s: %w", importPath, err)
+	}
+
+	pkg := &goPackage{
+		path:    importPath,
+		types:   make(map[string]*typeDecl),
+		funcs:   make(map[string]*funcDecl),
+		methods: make(map[string][]*funcDecl),
+		consts:  make(map[string][]interface{}),
+	}
+	s.pkgs[importPath] = pkg
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		src, err := fs.ReadFile(s.fsys, path.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		f, err := parser.ParseFile(s.fset, path.Join(dir, name), src, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		pkg.add(f)
+	}
+	return pkg, nil
+}
+
+// file parses a file of the project, by slash-separated path relative to
+// its root, along with the rest of its package.
+func (s *source) file(name string) (*goFile, error) {
+	pkg, err := s.load(path.Join(s.module, path.Dir(name)))
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range pkg.files {
+		if s.fset.Position(f.ast.Package).Filename == name {
+			return f, nil
+		}
+	}
+	return nil, fmt.Errorf("%s not found", name)
+}
+
+func (p *goPackage) add(f *ast.File) {
+	p.name = f.Name.Name
+	file := &goFile{pkg: p, ast: f, imports: make(map[string]string)}
+	p.files = append(p.files, file)
+	for _, imp := range f.Imports {
+		importPath, _ := strconv.Unquote(imp.Path.Value)
+		name := importName(importPath)
+		if imp.Name != nil {
+			name = imp.Name.Name
+		}
+		file.imports[name] = importPath
+	}
+
+	for _, decl := range f.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			fd := &funcDecl{decl: decl, file: file}
+			if decl.Recv == nil {
+				p.funcs[decl.Name.Name] = fd
+			} else {
+				p.methods[decl.Name.Name] = append(p.methods[decl.Name.Name], fd)
+			}
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					doc := spec.Doc
+					if doc == nil && len(decl.Specs) == 1 {
+						doc = decl.Doc
+					}
+					p.types[spec.Name.Name] = &typeDecl{spec: spec, doc: doc.Text(), file: file}
+				case *ast.ValueSpec:
+					p.addConsts(decl.Tok, spec)
+				}
+			}
+		}
+	}
+}
+
+// addConsts records constants of named types with literal values, the
+// values of enums.
+func (p *goPackage) addConsts(tok token.Token, spec *ast.ValueSpec) {
+	typ, ok := spec.Type.(*ast.Ident)
+	if tok != token.CONST || !ok {
+		return
+	}
+	for _, value := range spec.Values {
+		if v, ok := literal(value); ok {
+			p.consts[typ.Name] = append(p.consts[typ.Name], v)
+		}
+	}
+}
+
+var majorVersion = regexp.MustCompile(`^v[0-9]+$`)
+
+// importName guesses the name of an imported package from its path:
+// github.com/go-chi/chi/v5 is chi, gopkg.in/yaml.v3 is yaml.
+func importName(importPath string) string {
+	parts := strings.Split(importPath, "/")
+	name := parts[len(parts)-1]
+	if len(parts) > 1 && majorVersion.MatchString(name) {
+		name = parts[len(parts)-2]
+	}
+	name, _, _ = strings.Cut(name, ".")
+	name = strings.TrimPrefix(name, "go-")
+	return strings.ReplaceAll(name, "-", "")
+}
+
+// importOf returns the import path of a package selector, such as json in
+// json.NewDecoder, or "".
+func (f *goFile) importOf(x ast.Expr) string {
+	if id, ok := x.(*ast.Ident); ok && id.Obj == nil {
+		return f.imports[id.Name]
+	}
+	return ""
+}
+
+// importsPackage reports whether f imports the package at importPath.
+func (f *goFile) importsPackage(importPath string) bool {
+	for _, p := range f.imports {
+		if p == importPath {
+			return true
+		}
+	}
+	return false
+}
+
+// literal returns the value of a basic literal.
+func literal(expr ast.Expr) (interface{}, bool) {
+	lit, ok := expr.(*ast.BasicLit)
+	if !ok {
+		return nil, false
+	}
+	switch lit.Kind {
+	case token.STRING:
+		s, err := strconv.Unquote(lit.Value)
+		return s, err == nil
+	case token.INT:
+		n, err := strconv.ParseInt(lit.Value, 0, 64)
+		return n, err == nil
+	case token.FLOAT:
+		f, err := strconv.ParseFloat(lit.Value, 64)
+		return f, err == nil
+	}
+	return nil, false
+}
+
+// stringLit returns the value of a string literal, or of a concatenation
+// of them.
+func stringLit(expr ast.Expr) (string, bool) {
+	if bin, ok := expr.(*ast.BinaryExpr); ok && bin.Op == token.ADD {
+		left, ok1 := stringLit(bin.X)
+		right, ok2 := stringLit(bin.Y)
+		return left + right, ok1 && ok2
+	}
+	v, ok := literal(expr)
+	s, isString := v.(string)
+	return s, ok && isString
+}
@@ -0,0 +1,325 @@
+package codegen
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Spec is the subset of an OpenAPI 3 document used to generate code, and
+// generated by ExtractSpec. JSON specs are read as YAML.
+type Spec struct {
+	OpenAPI string `yaml:"openapi"`
+	Info    struct {
+		Title       string `yaml:"title"`
+		Description string `yaml:"description,omitempty"`
+		Version     string `yaml:"version"`
+	} `yaml:"info"`
+	Paths      map[string]*PathItem `yaml:"paths"`
+	Components struct {
+		Schemas       map[string]*Schema      `yaml:"schemas,omitempty"`
+		Parameters    map[string]*Parameter   `yaml:"parameters,omitempty"`
+		RequestBodies map[string]*RequestBody `yaml:"requestBodies,omitempty"`
+		Responses     map[string]*Response    `yaml:"responses,omitempty"`
+	} `yaml:"components,omitempty"`
+}
+
+// PathItem holds the operations of a path.
+type PathItem struct {
+	Parameters []*Parameter `yaml:"parameters,omitempty"`
+	Get        *Operation   `yaml:"get,omitempty"`
+	Put        *Operation   `yaml:"put,omitempty"`
+	Post       *Operation   `yaml:"post,omitempty"`
+	Delete     *Operation   `yaml:"delete,omitempty"`
+	Options    *Operation   `yaml:"options,omitempty"`
+	Head       *Operation   `yaml:"head,omitempty"`
+	Patch      *Operation   `yaml:"patch,omitempty"`
+}
+
+// Operations returns the operations of the path by HTTP method, in a fixed
+// order.
+func (p *PathItem) Operations() []MethodOperation {
+	var ops []MethodOperation
+	for _, op := range []MethodOperation{
+		{"GET", p.Get}, {"PUT", p.Put}, {"POST", p.Post}, {"DELETE", p.Delete},
+		{"OPTIONS", p.Options}, {"HEAD", p.Head}, {"PATCH", p.Patch},
+	} {
+		if op.Operation != nil {
+			ops = append(ops, op)
+		}
+	}
+	return ops
+}
+
+// SetOperation sets the operation of an HTTP method, and reports whether
+// the method is supported.
+func (p *PathItem) SetOperation(method string, op *Operation) bool {
+	switch method {
+	case "GET":
+		p.Get = op
+	case "PUT":
+		p.Put = op
+	case "POST":
+		p.Post = op
+	case "DELETE":
+		p.Delete = op
+	case "OPTIONS":
+		p.Options = op
+	case "HEAD":
+		p.Head = op
+	case "PATCH":
+		p.Patch = op
+	default:
+		return false
+	}
+	return true
+}
+
+// MethodOperation is an operation and its HTTP method.
+type MethodOperation struct {
+	Method    string
+	Operation *Operation
+}
+
+// Operation is an API operation.
+type Operation struct {
+	Tags        []string             `yaml:"tags,omitempty"`
+	Summary     string               `yaml:"summary,omitempty"`
+	Description string               `yaml:"description,omitempty"`
+	OperationID string               `yaml:"operationId,omitempty"`
+	Deprecated  bool                 `yaml:"deprecated,omitempty"`
+	Parameters  []*Parameter         `yaml:"parameters,omitempty"`
+	RequestBody *RequestBody         `yaml:"requestBody,omitempty"`
+	Responses   map[string]*Response `yaml:"responses"`
+}
+
+// Parameter is a path, query or header parameter.
+type Parameter struct {
+	Ref         string      `yaml:"$ref,omitempty"`
+	Name        string      `yaml:"name,omitempty"`
+	In          string      `yaml:"in,omitempty"`
+	Required    bool        `yaml:"required,omitempty"`
+	Description string      `yaml:"description,omitempty"`
+	Schema      *Schema     `yaml:"schema,omitempty"`
+	Example     interface{} `yaml:"example,omitempty"`
+}
+
+// RequestBody is the body of an operation.
+type RequestBody struct {
+	Ref      string                `yaml:"$ref,omitempty"`
+	Required bool                  `yaml:"required,omitempty"`
+	Content  map[string]*MediaType `yaml:"content,omitempty"`
+}
+
+// Response is a response of an operation.
+type Response struct {
+	Ref         string                `yaml:"$ref,omitempty"`
+	Description string                `yaml:"description,omitempty"`
+	Headers     map[string]*Header    `yaml:"headers,omitempty"`
+	Content     map[string]*MediaType `yaml:"content,omitempty"`
+}
+
+// Header is a header of a response.
+type Header struct {
+	Description string  `yaml:"description,omitempty"`
+	Schema      *Schema `yaml:"schema,omitempty"`
+}
+
+// MediaType is the content of a body for one media type.
+type MediaType struct {
+	Schema   *Schema             `yaml:"schema,omitempty"`
+	Example  interface{}         `yaml:"example,omitempty"`
+	Examples map[string]*Example `yaml:"examples,omitempty"`
+}
+
+// Example is a named example of a media type.
+type Example struct {
+	Ref   string      `yaml:"$ref,omitempty"`
+	Value interface{} `yaml:"value,omitempty"`
+}
+
+// Schema is a JSON schema.
+type Schema struct {
+	Ref                  string        `yaml:"$ref,omitempty"`
+	Type                 SchemaType    `yaml:"type,omitempty"`
+	Format               string        `yaml:"format,omitempty"`
+	Description          string        `yaml:"description,omitempty"`
+	Nullable             bool          `yaml:"nullable,omitempty"`
+	Enum                 []interface{} `yaml:"enum,omitempty"`
+	Properties           Properties    `yaml:"properties,omitempty"`
+	Required             []string      `yaml:"required,omitempty"`
+	Items                *Schema       `yaml:"items,omitempty"`
+	AdditionalProperties *Additional   `yaml:"additionalProperties,omitempty"`
+	Minimum              *float64      `yaml:"minimum,omitempty"`
+	Maximum              *float64      `yaml:"maximum,omitempty"`
+	MinLength            *int          `yaml:"minLength,omitempty"`
+	MaxLength            *int          `yaml:"maxLength,omitempty"`
+	MinItems             *int          `yaml:"minItems,omitempty"`
+	MaxItems             *int          `yaml:"maxItems,omitempty"`
+	AllOf                []*Schema     `yaml:"allOf,omitempty"`
+	OneOf                []*Schema     `yaml:"oneOf,omitempty"`
+	AnyOf                []*Schema     `yaml:"anyOf,omitempty"`
+	Default              interface{}   `yaml:"default,omitempty"`
+	Example              interface{}   `yaml:"example,omitempty"`
+}
+
+// SchemaType is the type of a schema. OpenAPI 3.1 lists, such as
+// [string, "null"], are reduced to their non-null type, and make the schema
+// Nullable.
+type SchemaType string
+
+func (t *SchemaType) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.SequenceNode {
+		var types []string
+		if err := node.Decode(&types); err != nil {
+			return err
+		}
+		for _, typ := range types {
+			if typ != "null" {
+				*t = SchemaType(typ)
+			}
+		}
+		return nil
+	}
+	return node.Decode((*string)(t))
+}
+
+func (s *Schema) UnmarshalYAML(node *yaml.Node) error {
+	type plain Schema
+	if err := node.Decode((*plain)(s)); err != nil {
+		return err
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value != "type" || node.Content[i+1].Kind != yaml.SequenceNode {
+			continue
+		}
+		for _, typ := range node.Content[i+1].Content {
+			if typ.Value == "null" {
+				s.Nullable = true
+			}
+		}
+	}
+	return nil
+}
+
+// MarshalYAML writes the type of a nullable schema as an OpenAPI 3.1 list,
+// such as [string, "null"], as 3.1 has no nullable.
+func (s *Schema) MarshalYAML() (interface{}, error) {
+	type plain Schema
+	if !s.Nullable || s.Type == "" {
+		return (*plain)(s), nil
+	}
+	c := *s
+	c.Nullable = false
+	node := &yaml.Node{}
+	if err := node.Encode((*plain)(&c)); err != nil {
+		return nil, err
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == "type" {
+			node.Content[i+1] = &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle, Content: []*yaml.Node{
+				{Kind: yaml.ScalarNode, Value: string(s.Type)},
+				{Kind: yaml.ScalarNode, Value: "null", Style: yaml.DoubleQuotedStyle},
+			}}
+		}
+	}
+	return node, nil
+}
+
+// Properties are the properties of an object schema, in the order of the
+// spec.
+type Properties []Property
+
+// Property is a named property of an object schema.
+type Property struct {
+	Name   string
+	Schema *Schema
+}
+
+func (p Properties) MarshalYAML() (interface{}, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for _, prop := range p {
+		value := &yaml.Node{}
+		if err := value.Encode(prop.Schema); err != nil {
+			return nil, err
+		}
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: prop.Name}, value)
+	}
+	return node, nil
+}
+
+func (p *Properties) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: properties must be a mapping", node.Line)
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		var schema Schema
+		if err := node.Content[i+1].Decode(&schema); err != nil {
+			return err
+		}
+		*p = append(*p, Property{Name: node.Content[i].Value, Schema: &schema})
+	}
+	return nil
+}
+
+// Additional is the additionalProperties of an object schema: either a
+// boolean or a schema.
+type Additional struct {
+	Allowed bool
+	Schema  *Schema
+}
+
+func (a *Additional) MarshalYAML() (interface{}, error) {
+	if a.Schema != nil {
+		return a.Schema, nil
+	}
+	return a.Allowed, nil
+}
+
+func (a *Additional) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		return node.Decode(&a.Allowed)
+	}
+	a.Allowed = true
+	return node.Decode(&a.Schema)
+}
+
+// LoadSpec reads an OpenAPI 3 spec in YAML or JSON.
+func LoadSpec(path string) (*Spec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spec: %w", err)
+	}
+	return ParseSpec(data)
+}
+
+// ParseSpec parses an OpenAPI 3 spec in YAML or JSON.
+func ParseSpec(data []byte) (*Spec, error) {
+	var version struct {
+		OpenAPI string `yaml:"openapi"`
+	}
+	if err := yaml.Unmarshal(data, &version); err != nil {
+		return nil, fmt.Errorf("failed to parse spec: %w", err)
+	}
+	if !strings.HasPrefix(version.OpenAPI, "3.") {
+		return nil, fmt.Errorf("unsupported spec: openapi version %q, want 3.x", version.OpenAPI)
+	}
+
+	var spec Spec
+	if err := yaml.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("failed to parse spec: %w", err)
+	}
+	return &spec, nil
+}
+
+// refName returns the component name of a local reference, e.g. User for
+// #/components/schemas/User.
+func refName(ref, kind string) (string, error) {
+	prefix := "#/components/" + kind + "/"
+	if !strings.HasPrefix(ref, prefix) {
+		return "", fmt.Errorf("unsupported reference %q: only %s... references are supported", ref, prefix)
+	}
+	return strings.TrimPrefix(ref, prefix), nil
+}
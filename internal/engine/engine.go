@@ -1,21 +1,25 @@
 package engine
 
 import (
+	"bytes"
 	"fmt"
+	"go/parser"
+	"go/token"
 	"io"
 	"io/fs"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
-	"text/template"
 
+	"github.com/TRiZKy/gocrete/internal/codegen"
 	"github.com/TRiZKy/gocrete/internal/modules"
-	"github.com/TRiZKy/gocrete/pkg/templates"
+	"github.com/TRiZKy/gocrete/internal/render"
+	"github.com/TRiZKy/gocrete/internal/routers"
 )
 
-var templatesFS = templates.FS
-
 type AddOptions struct {
 	Module string
 	Type   string
@@ -23,13 +27,26 @@ type AddOptions struct {
 	Spec   string
 }
 
+// GenerateOptions configure code generation from a project's spec.
+type GenerateOptions struct {
+	Spec    string
+	Output  string
+	Package string
+	// Check makes GenerateSpec fail if the spec is out of date instead of
+	// writing it.
+	Check bool
+}
+
 type Engine struct {
 	registry *modules.Registry
+	// Progress receives the progress messages, os.Stdout by default.
+	Progress io.Writer
 }
 
 func NewEngine() *Engine {
 	return &Engine{
 		registry: modules.NewRegistry(),
+		Progress: os.Stdout,
 	}
 }
 
@@ -50,88 +67,61 @@ func (e *Engine) InitProject(projectPath string, opts modules.InitOptions) error
 		return fmt.Errorf("failed to create project directory: %w", err)
 	}
 
-	// Create context
-	ctx := &modules.Context{
-		ProjectPath: projectPath,
-		Options:     opts,
-		TemplateData: map[string]interface{}{
-			"ProjectName": opts.ProjectName,
-			"ModulePath":  opts.ModulePath,
-			"Router":      opts.Router,
-			"Database":    opts.Database,
-			"OpenAPI":     opts.OpenAPI,
-			"Migrations":  opts.Migrations,
-			"HasDocker":   opts.Docker,
-		},
+	if err := e.generate(render.Dir(projectPath), opts); err != nil {
+		return err
 	}
 
-	// Apply base template
-	fmt.Println("→ Applying base template...")
-	if err := e.applyTemplate("files/base", projectPath, ctx.TemplateData); err != nil {
-		return fmt.Errorf("failed to apply base template: %w", err)
+	// Run post-generation steps
+	fmt.Fprintln(e.Progress, "→ Running post-generation steps...")
+	if err := e.runPostSteps(projectPath, opts); err != nil {
+		return fmt.Errorf("post-generation steps failed: %w", err)
 	}
 
-	// Apply database module
-	if opts.Database != "none" {
-		fmt.Printf("→ Adding %s database...\n", opts.Database)
-		mod := e.registry.GetModule("db", opts.Database)
-		if mod == nil {
-			return fmt.Errorf("database module %s not found", opts.Database)
-		}
-		if err := mod.Apply(ctx); err != nil {
-			return fmt.Errorf("failed to apply database module: %w", err)
-		}
-	}
+	return nil
+}
 
-	// Apply OpenAPI module
-	if opts.OpenAPI != "none" {
-		fmt.Printf("→ Adding OpenAPI (%s mode)...\n", opts.OpenAPI)
-		mod := e.registry.GetModule("openapi", opts.OpenAPI)
-		if mod == nil {
-			return fmt.Errorf("openapi module %s not found", opts.OpenAPI)
-		}
-		if err := mod.Apply(ctx); err != nil {
-			return fmt.Errorf("failed to apply openapi module: %w", err)
-		}
+// InitArchive writes a new project to w as an archive in format, render.Tar
+// or render.Zip, with the files InitProject creates under a directory named
+// after the project. The project is created in a temporary directory, for
+// the go command to write go.mod and go.sum, and removed once archived.
+func (e *Engine) InitArchive(w io.Writer, format string, opts modules.InitOptions) error {
+	out, err := render.NewArchive(w, format)
+	if err != nil {
+		return err
 	}
 
-	// Apply Docker module
-	if opts.Docker {
-		fmt.Println("→ Adding Docker configuration...")
-		mod := e.registry.GetModule("docker", "")
-		if mod == nil {
-			return fmt.Errorf("docker module not found")
-		}
-		if err := mod.Apply(ctx); err != nil {
-			return fmt.Errorf("failed to apply docker module: %w", err)
-		}
+	tmpDir, err := os.MkdirTemp("", "gocrete-")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
 	}
+	defer os.RemoveAll(tmpDir)
 
-	// Run post-generation steps
-	fmt.Println("→ Running post-generation steps...")
-	if err := e.runPostSteps(projectPath, opts); err != nil {
-		return fmt.Errorf("post-generation steps failed: %w", err)
+	projectPath := filepath.Join(tmpDir, opts.ProjectName)
+	if err := e.InitProject(projectPath, opts); err != nil {
+		return err
 	}
 
-	return nil
+	fmt.Fprintf(e.Progress, "→ Writing %s archive...\n", format)
+	if err := render.Copy(out, os.DirFS(projectPath), opts.ProjectName); err != nil {
+		return fmt.Errorf("failed to archive project: %w", err)
+	}
+	return out.Close()
 }
 
 func (e *Engine) AddModule(projectPath string, opts AddOptions) error {
-	// Read existing go.mod to get module path
-	modPath, err := e.getModulePath(projectPath)
+	// Detect the options the project was generated with
+	projectOpts, err := e.detectProject(projectPath)
 	if err != nil {
 		return err
 	}
 
 	// Create context
 	ctx := &modules.Context{
-		ProjectPath: projectPath,
-		Options: modules.InitOptions{
-			ModulePath: modPath,
-		},
-		TemplateData: map[string]interface{}{
-			"ModulePath": modPath,
-		},
+		Output:       render.Dir(projectPath),
+		Progress:     e.Progress,
+		Options:      projectOpts,
+		TemplateData: templateData(projectOpts),
+		PreviousData: templateData(projectOpts),
 	}
 
 	// Get and apply module
@@ -151,11 +141,46 @@ func (e *Engine) AddModule(projectPath string, opts AddOptions) error {
 		mod = e.registry.GetModule("openapi", opts.Mode)
 		ctx.Options.OpenAPI = opts.Mode
 		ctx.Options.SpecPath = opts.Spec
+		if ctx.Spec, err = readSpec(opts.Spec); err != nil {
+			return err
+		}
 		ctx.TemplateData["OpenAPI"] = opts.Mode
 	case "docker":
 		mod = e.registry.GetModule("docker", "")
 		ctx.Options.Docker = true
 		ctx.TemplateData["HasDocker"] = true
+	case "outbox":
+		if ctx.Options.Database != "postgres" {
+			return fmt.Errorf("outbox module requires a postgres database (run: gocrete add db --type postgres)")
+		}
+		mod = e.registry.GetModule("outbox", "")
+		ctx.Options.Outbox = true
+		ctx.TemplateData["HasOutbox"] = true
+	case "worker":
+		mod = e.registry.GetModule("worker", "")
+		ctx.Options.Worker = true
+		ctx.TemplateData["HasWorker"] = true
+	case "auth":
+		if opts.Type == "" {
+			return fmt.Errorf("--type flag is required for auth module")
+		}
+		mod = e.registry.GetModule("auth", opts.Type)
+		if !ctx.Options.HasAuth(opts.Type) {
+			ctx.Options.Auth = append(ctx.Options.Auth, opts.Type)
+		}
+		ctx.TemplateData = templateData(ctx.Options)
+	case "rbac":
+		mod = e.registry.GetModule("rbac", "")
+		ctx.Options.RBAC = true
+		ctx.TemplateData["HasRBAC"] = true
+	case "metrics":
+		mod = e.registry.GetModule("metrics", "")
+		ctx.Options.Metrics = true
+		ctx.TemplateData["HasMetrics"] = true
+	case "tracing":
+		mod = e.registry.GetModule("tracing", "")
+		ctx.Options.Tracing = true
+		ctx.TemplateData["HasTracing"] = true
 	default:
 		return fmt.Errorf("unknown module: %s", opts.Module)
 	}
@@ -169,75 +194,348 @@ func (e *Engine) AddModule(projectPath string, opts AddOptions) error {
 	}
 
 	// Run go mod tidy
-	fmt.Println("→ Running go mod tidy...")
+	fmt.Fprintln(e.Progress, "→ Running go mod tidy...")
 	cmd := exec.Command("go", "mod", "tidy")
 	cmd.Dir = projectPath
 	if output, err := cmd.CombinedOutput(); err != nil {
-		fmt.Printf("Warning: go mod tidy failed: %s\n", output)
+		fmt.Fprintf(e.Progress, "Warning: go mod tidy failed: %s\n", output)
 	}
 
+	// Format code, since modules may re-render base files
+	cmd = exec.Command("go", "fmt", "./...")
+	cmd.Dir = projectPath
+	cmd.Run() // Ignore errors for formatting
+
 	return nil
 }
 
-func (e *Engine) applyTemplate(templatePath, destPath string, data map[string]interface{}) error {
-	return fs.WalkDir(templatesFS, templatePath, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return err
+// GenerateClient generates a Go client package for the project's OpenAPI
+// spec. Relative paths in opts are resolved against projectPath.
+func (e *Engine) GenerateClient(projectPath string, opts GenerateOptions) error {
+	resolve := func(p string) string {
+		if filepath.IsAbs(p) {
+			return p
 		}
+		return filepath.Join(projectPath, p)
+	}
 
-		// Skip the root directory
-		if path == templatePath {
-			return nil
+	fmt.Fprintf(e.Progress, "→ Generating client from %s...\n", opts.Spec)
+	files, err := codegen.GenerateClient(codegen.ClientOptions{
+		SpecPath:  resolve(opts.Spec),
+		OutputDir: resolve(opts.Output),
+		Package:   opts.Package,
+	})
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		fmt.Fprintf(e.Progress, "  wrote %s\n", file)
+	}
+	return nil
+}
+
+// GenerateSpec writes the spec built from the routes and handlers of a
+// project in manual OpenAPI mode to opts.Output. Gen mode projects are
+// generated from their spec, not the other way around.
+func (e *Engine) GenerateSpec(projectPath string, opts GenerateOptions) error {
+	project, err := e.detectProject(projectPath)
+	if err != nil {
+		return err
+	}
+	if project.OpenAPI == "gen" {
+		return fmt.Errorf("the project is in gen mode: its code is generated from the spec")
+	}
+
+	output := opts.Output
+	if !filepath.IsAbs(output) {
+		output = filepath.Join(projectPath, output)
+	}
+	existing, err := os.ReadFile(output)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read spec: %w", err)
+	}
+	// Keep the title, description and version of the existing spec
+	var base *codegen.Spec
+	if len(existing) > 0 {
+		base, _ = codegen.ParseSpec(existing)
+	}
+
+	fmt.Fprintf(e.Progress, "→ Extracting spec from %s...\n", codegen.ServerFile)
+	spec, err := codegen.ExtractSpec(projectPath, base)
+	if err != nil {
+		return err
+	}
+	data, err := codegen.MarshalSpec(spec)
+	if err != nil {
+		return err
+	}
+
+	if opts.Check {
+		if !bytes.Equal(data, existing) {
+			return fmt.Errorf("%s is out of date; run gocrete generate spec", opts.Output)
 		}
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(output), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := os.WriteFile(output, data, 0644); err != nil {
+		return fmt.Errorf("failed to write spec: %w", err)
+	}
+	fmt.Fprintf(e.Progress, "  wrote %s\n", opts.Output)
+	return nil
+}
 
-		// Calculate relative path
-		relPath, err := filepath.Rel(templatePath, path)
-		if err != nil {
-			return err
+// GenerateContractTests writes contract tests of the project's server
+// against its spec to internal/http: contract_test.go, regenerated each
+// time, and contract_fixture_test.go, which configures the server and is
+// only written if missing.
+func (e *Engine) GenerateContractTests(projectPath string) error {
+	project, err := e.detectProject(projectPath)
+	if err != nil {
+		return err
+	}
+	if project.OpenAPI == "none" {
+		return fmt.Errorf("the project has no OpenAPI spec: add one with gocrete add openapi")
+	}
+	fmt.Fprintln(e.Progress, "→ Generating contract tests from api/openapi.yaml...")
+	files, err := renderContractTests(projectPath, project)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Join(projectPath, "internal", "http")
+	for _, name := range []string{"contract_test.go", "contract_fixture_test.go"} {
+		dest := filepath.Join(dir, name)
+		if name == "contract_fixture_test.go" {
+			if _, err := os.Stat(dest); err == nil {
+				fmt.Fprintf(e.Progress, "  kept %s\n", filepath.Join("internal", "http", name))
+				continue
+			}
+		}
+		if err := os.WriteFile(dest, files[name], 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", dest, err)
 		}
+		fmt.Fprintf(e.Progress, "  wrote %s\n", filepath.Join("internal", "http", name))
+	}
 
-		destFilePath := filepath.Join(destPath, relPath)
+	// Fiber servers generated before App existed can't be tested
+	if project.Router == "fiber" {
+		server, _ := os.ReadFile(filepath.Join(dir, "server.go"))
+		if !strings.Contains(string(server), "func (s *Server) App() *fiber.App") {
+			fmt.Fprintln(e.Progress, "  ⚠ internal/http/server.go has no App method, add it for the tests:")
+			fmt.Fprintln(e.Progress, "      func (s *Server) App() *fiber.App { return s.app }")
+		}
+	}
+	return nil
+}
 
-		if d.IsDir() {
-			return os.MkdirAll(destFilePath, 0755)
+// renderContractTests renders the contract tests of a project. The tests
+// check responses against the spec embedded by the api package.
+func renderContractTests(projectPath string, project modules.InitOptions) (map[string][]byte, error) {
+	spec, err := codegen.LoadSpec(filepath.Join(projectPath, "api", "openapi.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	cases, err := codegen.ContractCases(spec)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported spec: %w", err)
+	}
+	if len(cases) == 0 {
+		return nil, fmt.Errorf("spec api/openapi.yaml has no operations")
+	}
+	return codegen.RenderContractTests(cases, templateData(project))
+}
+
+// MigrateRouter switches a project to another router. The files gocrete
+// generated for the router are rendered again for the new one, and those
+// changed since they were generated are kept next to them with a .orig
+// suffix. Handlers with the net/http shape are left as they are; the other
+// files using the previous router are reported for manual migration.
+func (e *Engine) MigrateRouter(projectPath, router string) error {
+	target := routers.Get(router)
+	if target == nil {
+		return invalidRouter(router)
+	}
+	project, err := e.detectProject(projectPath)
+	if err != nil {
+		return err
+	}
+	if project.Router == router {
+		return fmt.Errorf("the project already uses %s", router)
+	}
+	previous := routers.Get(project.Router)
+	migrated := project
+	migrated.Router = router
+
+	fmt.Fprintf(e.Progress, "→ Migrating from %s to %s...\n", project.Router, router)
+	files, err := modules.RouterFiles()
+	if err != nil {
+		return err
+	}
+	var attention []string
+	rendered := map[string]bool{}
+	update := func(file string, generated, content []byte) error {
+		note, err := rewrite(projectPath, file, generated, content)
+		if err != nil {
+			return err
 		}
+		if note != "" {
+			attention = append(attention, note)
+		}
+		rendered[file] = true
+		fmt.Fprintf(e.Progress, "  updated %s\n", file)
+		return nil
+	}
 
-		// Read file content
-		content, err := templatesFS.ReadFile(path)
+	for _, file := range files {
+		if _, err := os.Stat(filepath.Join(projectPath, filepath.FromSlash(file.Path))); os.IsNotExist(err) {
+			// The file's module isn't in the project
+			continue
+		}
+		generated, err := render.File(file.Template, file.File, templateData(project))
 		if err != nil {
 			return err
 		}
+		content, err := render.File(file.Template, file.File, templateData(migrated))
+		if err != nil {
+			return err
+		}
+		if err := update(file.Path, generated, content); err != nil {
+			return err
+		}
+	}
 
-		// Check if file should be templated
-		if strings.HasSuffix(path, ".tmpl") {
-			destFilePath = strings.TrimSuffix(destFilePath, ".tmpl")
-			content, err = e.renderTemplate(string(content), data)
-			if err != nil {
-				return fmt.Errorf("failed to render template %s: %w", path, err)
-			}
+	// Contract tests exercise the router
+	const contractTests = "internal/http/contract_test.go"
+	if _, err := os.Stat(filepath.Join(projectPath, contractTests)); err == nil {
+		generated, err := renderContractTests(projectPath, project)
+		if err != nil {
+			return err
+		}
+		tests, err := renderContractTests(projectPath, migrated)
+		if err != nil {
+			return err
+		}
+		if err := update(contractTests, generated["contract_test.go"], tests["contract_test.go"]); err != nil {
+			return err
 		}
+	}
 
-		// Write file
-		if err := os.MkdirAll(filepath.Dir(destFilePath), 0755); err != nil {
+	// Gen mode servers implement the interface generated for the router
+	if project.OpenAPI == "gen" {
+		generated, err := render.File("files/openapi/gen", "Makefile.tmpl", templateData(project))
+		if err != nil {
+			return err
+		}
+		content, err := render.File("files/openapi/gen", "Makefile.tmpl", templateData(migrated))
+		if err != nil {
+			return err
+		}
+		if err := update("Makefile", generated, content); err != nil {
 			return err
 		}
+		if _, err := os.Stat(filepath.Join(projectPath, "internal", "api", "generated")); err == nil {
+			attention = append(attention, "internal/api/generated: regenerate it with make api-gen, and update the implementations of its ServerInterface")
+		}
+	}
 
-		return os.WriteFile(destFilePath, content, 0644)
-	})
+	// Report the other code written for the previous router
+	others, err := routerUsers(projectPath, previous, rendered)
+	if err != nil {
+		return err
+	}
+	attention = append(attention, others...)
+
+	fmt.Fprintln(e.Progress, "→ Running go mod tidy...")
+	cmd := exec.Command("go", "mod", "tidy")
+	cmd.Dir = projectPath
+	if output, err := cmd.CombinedOutput(); err != nil {
+		fmt.Fprintf(e.Progress, "Warning: go mod tidy failed: %s\n", output)
+	}
+
+	cmd = exec.Command("go", "fmt", "./...")
+	cmd.Dir = projectPath
+	cmd.Run() // Ignore errors for formatting
+
+	if len(attention) > 0 {
+		fmt.Fprintln(e.Progress, "\n⚠ Files needing manual attention:")
+		for _, a := range attention {
+			fmt.Fprintf(e.Progress, "  %s\n", a)
+		}
+	}
+	return nil
 }
 
-func (e *Engine) renderTemplate(content string, data map[string]interface{}) ([]byte, error) {
-	tmpl, err := template.New("template").Parse(content)
+// rewrite writes content to the project file, first keeping its current
+// content in a .orig file if it differs from generated, the content it was
+// generated with. It returns the note reporting a kept file, if any.
+func rewrite(projectPath, file string, generated, content []byte) (string, error) {
+	dest := filepath.Join(projectPath, filepath.FromSlash(file))
+	current, err := os.ReadFile(dest)
 	if err != nil {
-		return nil, err
+		return "", fmt.Errorf("failed to read %s: %w", file, err)
 	}
 
-	var buf strings.Builder
-	if err := tmpl.Execute(&buf, data); err != nil {
-		return nil, err
+	var note string
+	if !render.SameSource(current, generated) {
+		if err := os.WriteFile(dest+".orig", current, 0644); err != nil {
+			return "", fmt.Errorf("failed to back up %s: %w", file, err)
+		}
+		note = fmt.Sprintf("%s: changed since it was generated, port the changes kept in %s.orig", file, path.Base(file))
+	}
+	if err := os.WriteFile(dest, content, 0644); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", file, err)
 	}
+	return note, nil
+}
+
+// routerUsers lists the Go files of a project, other than those in skip,
+// that use router: that import its packages or, for the standard library's,
+// read the path parameters only set by http.ServeMux.
+func routerUsers(projectPath string, router *routers.Adapter, skip map[string]bool) ([]string, error) {
+	var users []string
+	err := filepath.WalkDir(projectPath, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(projectPath, p)
+		rel = filepath.ToSlash(rel)
+		if d.IsDir() {
+			// Generated code is regenerated, not migrated
+			if rel == "vendor" || rel == "internal/api/generated" || (rel != "." && strings.HasPrefix(d.Name(), ".")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(p, ".go") || skip[rel] {
+			return nil
+		}
 
-	return []byte(buf.String()), nil
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		if router.Module == "" {
+			if bytes.Contains(content, []byte(".PathValue(")) {
+				users = append(users, rel+": reads path parameters with PathValue, set by http.ServeMux")
+			}
+			return nil
+		}
+		file, err := parser.ParseFile(token.NewFileSet(), p, content, parser.ImportsOnly)
+		if err != nil {
+			return nil
+		}
+		for _, imp := range file.Imports {
+			importPath, _ := strconv.Unquote(imp.Path.Value)
+			if importPath == router.Module || strings.HasPrefix(importPath, router.Module+"/") {
+				users = append(users, rel+": imports "+importPath)
+				break
+			}
+		}
+		return nil
+	})
+	return users, err
 }
 
 func (e *Engine) runPostSteps(projectPath string, opts modules.InitOptions) error {
@@ -265,9 +563,8 @@ func (e *Engine) runPostSteps(projectPath string, opts modules.InitOptions) erro
 
 func (e *Engine) validateInitOptions(opts modules.InitOptions) error {
 	// Validate router
-	validRouters := map[string]bool{"chi": true, "gin": true, "fiber": true}
-	if !validRouters[opts.Router] {
-		return fmt.Errorf("invalid router: %s (must be chi, gin, or fiber)", opts.Router)
+	if routers.Get(opts.Router) == nil {
+		return invalidRouter(opts.Router)
 	}
 
 	// Validate database
@@ -291,6 +588,116 @@ func (e *Engine) validateInitOptions(opts modules.InitOptions) error {
 	return nil
 }
 
+// invalidRouter returns the error for an unknown router.
+func invalidRouter(router string) error {
+	names := routers.Names()
+	last := len(names) - 1
+	return fmt.Errorf("invalid router: %s (must be %s, or %s)", router, strings.Join(names[:last], ", "), names[last])
+}
+
+// templateData builds the data passed to every template from the project
+// options. NetHTTP is set for the routers whose handlers and middleware are
+// plain net/http ones, and OpenAPIServer is the oapi-codegen generator of the
+// router; templates render the rest of the router specific code with the
+// router function.
+func templateData(opts modules.InitOptions) map[string]interface{} {
+	router := routers.Get(opts.Router)
+	if router == nil {
+		router = &routers.Adapter{}
+	}
+	return map[string]interface{}{
+		"ProjectName":   opts.ProjectName,
+		"ModulePath":    opts.ModulePath,
+		"Router":        opts.Router,
+		"NetHTTP":       router.NetHTTP,
+		"OpenAPIServer": router.OpenAPIServer,
+		"Database":      opts.Database,
+		"OpenAPI":       opts.OpenAPI,
+		"Migrations":    opts.Migrations,
+		"HasDocker":     opts.Docker,
+		"HasWorker":     opts.Worker,
+		"HasOutbox":     opts.Outbox,
+		"HasAuth":       len(opts.Auth) > 0,
+		"HasJWT":        opts.HasAuth("jwt"),
+		"HasOIDC":       opts.HasAuth("oidc"),
+		"HasAPIKey":     opts.HasAuth("apikey"),
+		"HasRBAC":       opts.RBAC,
+		"HasMetrics":    opts.Metrics,
+		"HasTracing":    opts.Tracing,
+	}
+}
+
+// detectProject reconstructs the init options of an existing project from
+// its go.mod and the files gocrete generated into it.
+func (e *Engine) detectProject(projectPath string) (modules.InitOptions, error) {
+	modPath, err := e.getModulePath(projectPath)
+	if err != nil {
+		return modules.InitOptions{}, err
+	}
+
+	goMod, err := os.ReadFile(filepath.Join(projectPath, "go.mod"))
+	if err != nil {
+		return modules.InitOptions{}, fmt.Errorf("failed to read go.mod: %w", err)
+	}
+	requires := func(dep string) bool {
+		return strings.Contains(string(goMod), dep+" ")
+	}
+	exists := func(rel string) bool {
+		_, err := os.Stat(filepath.Join(projectPath, rel))
+		return err == nil
+	}
+	makefile, _ := os.ReadFile(filepath.Join(projectPath, "Makefile"))
+
+	opts := modules.InitOptions{
+		ProjectName: path.Base(modPath),
+		ModulePath:  modPath,
+		Router:      routers.Default,
+		Database:    "none",
+		OpenAPI:     "none",
+		Migrations:  "none",
+		Docker:      exists("Dockerfile"),
+		Worker:      exists("cmd/worker"),
+		Outbox:      exists("cmd/relay"),
+		RBAC:        exists("internal/authz/policy.go"),
+		Metrics:     exists("internal/metrics/metrics.go"),
+		Tracing:     exists("internal/telemetry/telemetry.go"),
+	}
+
+	if router := routers.Detect(requires); router != nil {
+		opts.Router = router.Name
+	}
+
+	switch {
+	case exists("internal/db/postgres"):
+		opts.Database = "postgres"
+	case exists("internal/db/mongo"):
+		opts.Database = "mongo"
+	}
+
+	switch {
+	case strings.Contains(string(makefile), "oapi-codegen"):
+		opts.OpenAPI = "gen"
+	case exists("internal/api/handlers"):
+		opts.OpenAPI = "manual"
+	}
+
+	if exists("migrations") {
+		opts.Migrations = "goose"
+	}
+
+	if exists("internal/auth/jwt_middleware.go") {
+		opts.Auth = append(opts.Auth, "jwt")
+	}
+	if exists("internal/auth/oidc.go") {
+		opts.Auth = append(opts.Auth, "oidc")
+	}
+	if exists("internal/auth/apikey.go") {
+		opts.Auth = append(opts.Auth, "apikey")
+	}
+
+	return opts, nil
+}
+
 func (e *Engine) getModulePath(projectPath string) (string, error) {
 	modFile := filepath.Join(projectPath, "go.mod")
 	content, err := os.ReadFile(modFile)
@@ -307,24 +714,3 @@ func (e *Engine) getModulePath(projectPath string) (string, error) {
 
 	return "", fmt.Errorf("module path not found in go.mod")
 }
-
-func CopyFile(src, dst string) error {
-	sourceFile, err := os.Open(src)
-	if err != nil {
-		return err
-	}
-	defer sourceFile.Close()
-
-	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
-		return err
-	}
-
-	destFile, err := os.Create(dst)
-	if err != nil {
-		return err
-	}
-	defer destFile.Close()
-
-	_, err = io.Copy(destFile, sourceFile)
-	return err
-}
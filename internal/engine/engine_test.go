@@ -4,20 +4,29 @@
 package engine
 
 import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"io/fs"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/TRiZKy/gocrete/internal/modules"
+	"github.com/TRiZKy/gocrete/internal/render"
 )
 
 func TestEngineValidateInitOptions(t *testing.T) {
 	tests := []struct {
 		name    string
-		opts    InitOptions
+		opts    modules.InitOptions
 		wantErr bool
 	}{
 		{
 			name: "valid chi router",
-			opts: InitOptions{
+			opts: modules.InitOptions{
 				ProjectName: "test",
 				ModulePath:  "github.com/test/test",
 				Router:      "chi",
@@ -29,7 +38,7 @@ func TestEngineValidateInitOptions(t *testing.T) {
 		},
 		{
 			name: "invalid router",
-			opts: InitOptions{
+			opts: modules.InitOptions{
 				ProjectName: "test",
 				ModulePath:  "github.com/test/test",
 				Router:      "invalid",
@@ -38,7 +47,7 @@ func TestEngineValidateInitOptions(t *testing.T) {
 		},
 		{
 			name: "invalid database",
-			opts: InitOptions{
+			opts: modules.InitOptions{
 				ProjectName: "test",
 				ModulePath:  "github.com/test/test",
 				Router:      "chi",
@@ -48,7 +57,7 @@ func TestEngineValidateInitOptions(t *testing.T) {
 		},
 		{
 			name: "invalid openapi",
-			opts: InitOptions{
+			opts: modules.InitOptions{
 				ProjectName: "test",
 				ModulePath:  "github.com/test/test",
 				Router:      "chi",
@@ -70,59 +79,6 @@ func TestEngineValidateInitOptions(t *testing.T) {
 	}
 }
 
-func TestEngineRenderTemplate(t *testing.T) {
-	tests := []struct {
-		name     string
-		template string
-		data     map[string]interface{}
-		want     string
-		wantErr  bool
-	}{
-		{
-			name:     "simple variable",
-			template: "Hello {{.Name}}",
-			data:     map[string]interface{}{"Name": "World"},
-			want:     "Hello World",
-			wantErr:  false,
-		},
-		{
-			name:     "conditional",
-			template: "{{if .Show}}Visible{{end}}",
-			data:     map[string]interface{}{"Show": true},
-			want:     "Visible",
-			wantErr:  false,
-		},
-		{
-			name:     "conditional false",
-			template: "{{if .Show}}Visible{{end}}",
-			data:     map[string]interface{}{"Show": false},
-			want:     "",
-			wantErr:  false,
-		},
-		{
-			name:     "invalid template",
-			template: "{{.InvalidSyntax",
-			data:     map[string]interface{}{},
-			want:     "",
-			wantErr:  true,
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			e := NewEngine()
-			got, err := e.renderTemplate(tt.template, tt.data)
-			if (err != nil) != tt.wantErr {
-				t.Errorf("renderTemplate() error = %v, wantErr %v", err, tt.wantErr)
-				return
-			}
-			if !tt.wantErr && string(got) != tt.want {
-				t.Errorf("renderTemplate() = %v, want %v", string(got), tt.want)
-			}
-		})
-	}
-}
-
 func TestEngineGetModulePath(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -170,7 +126,7 @@ func TestInitProjectIntegration(t *testing.T) {
 	projectPath := filepath.Join(tmpDir, "test-project")
 
 	e := NewEngine()
-	opts := InitOptions{
+	opts := modules.InitOptions{
 		ProjectName: "test-project",
 		ModulePath:  "github.com/test/project",
 		Router:      "chi",
@@ -207,3 +163,297 @@ func TestInitProjectIntegration(t *testing.T) {
 		}
 	}
 }
+
+// TestInitProjectCompiles generates a project for every router, with the
+// modules that have router specific code, and builds, vets and tests it.
+func TestInitProjectCompiles(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	for _, router := range []string{"chi", "gin", "fiber", "echo", "stdlib"} {
+		t.Run(router, func(t *testing.T) {
+			projectPath := filepath.Join(t.TempDir(), "app")
+
+			e := NewEngine()
+			opts := modules.InitOptions{
+				ProjectName: "app",
+				ModulePath:  "example.com/app",
+				Router:      router,
+				Database:    "none",
+				OpenAPI:     "manual",
+				Migrations:  "none",
+			}
+			if err := e.InitProject(projectPath, opts); err != nil {
+				t.Fatalf("InitProject() error = %v", err)
+			}
+			for _, add := range []AddOptions{
+				{Module: "metrics"},
+				{Module: "auth", Type: "jwt"},
+				{Module: "auth", Type: "apikey"},
+				{Module: "auth", Type: "oidc"},
+				{Module: "rbac"},
+			} {
+				if err := e.AddModule(projectPath, add); err != nil {
+					t.Fatalf("AddModule(%+v) error = %v", add, err)
+				}
+			}
+			err := filepath.WalkDir(projectPath, func(p string, d fs.DirEntry, err error) error {
+				if err == nil && strings.HasSuffix(p, ".new") {
+					t.Errorf("AddModule() kept an unchanged file: wrote %s", p)
+				}
+				return err
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, args := range [][]string{{"build", "./..."}, {"vet", "./..."}, {"test", "./..."}} {
+				cmd := exec.Command("go", args...)
+				cmd.Dir = projectPath
+				if output, err := cmd.CombinedOutput(); err != nil {
+					t.Errorf("go %s failed: %s", strings.Join(args, " "), output)
+				}
+			}
+		})
+	}
+}
+
+// TestAddModuleKeepsChanges checks that adding a module keeps the base files
+// changed since they were generated, writing the new content next to them.
+func TestAddModuleKeepsChanges(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	projectPath := filepath.Join(t.TempDir(), "app")
+	e := NewEngine()
+	opts := modules.InitOptions{
+		ProjectName: "app",
+		ModulePath:  "example.com/app",
+		Router:      "chi",
+		Database:    "none",
+		OpenAPI:     "none",
+		Migrations:  "none",
+	}
+	if err := e.InitProject(projectPath, opts); err != nil {
+		t.Fatalf("InitProject() error = %v", err)
+	}
+
+	server := filepath.Join(projectPath, "internal", "http", "server.go")
+	content, err := os.ReadFile(server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed := append(content, "\n// Local change\n"...)
+	if err := os.WriteFile(server, changed, 0644); err != nil {
+		t.Fatal(err)
+	}
+	config := filepath.Join(projectPath, "internal", "config", "config.go")
+
+	if err := e.AddModule(projectPath, AddOptions{Module: "auth", Type: "jwt"}); err != nil {
+		t.Fatalf("AddModule() error = %v", err)
+	}
+
+	if got, err := os.ReadFile(server); err != nil || string(got) != string(changed) {
+		t.Errorf("server.go = %q, %v; want the changed server.go kept", got, err)
+	}
+	if got, err := os.ReadFile(server + ".new"); err != nil || !strings.Contains(string(got), "JWT") {
+		t.Errorf("server.go.new = %q, %v; want the server wiring JWT auth", got, err)
+	}
+	if got, err := os.ReadFile(config); err != nil || !strings.Contains(string(got), "JWTSecret") {
+		t.Errorf("config.go = %q, %v; want the JWT configuration", got, err)
+	}
+	if _, err := os.Stat(config + ".new"); err == nil {
+		t.Error("AddModule() wrote config.go.new for an unchanged config.go")
+	}
+}
+
+// TestInitArchive checks that a project archive is reproducible and builds
+// once extracted.
+func TestInitArchive(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go command not available")
+	}
+
+	opts := modules.InitOptions{
+		ProjectName: "archived",
+		ModulePath:  "github.com/test/archived",
+		Router:      "chi",
+		Database:    "postgres",
+		OpenAPI:     "none",
+		Migrations:  "goose",
+	}
+
+	write := func() []byte {
+		var buf bytes.Buffer
+		if err := NewEngine().InitArchive(&buf, render.Tar, opts); err != nil {
+			t.Fatalf("InitArchive() error = %v", err)
+		}
+		return buf.Bytes()
+	}
+	archive := write()
+	if !bytes.Equal(archive, write()) {
+		t.Error("archives of the same project differ")
+	}
+
+	// Extract the project and build it
+	projectPath := t.TempDir()
+	tr := tar.NewReader(bytes.NewReader(archive))
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("reading archive: %v", err)
+		}
+		if !strings.HasPrefix(hdr.Name, "archived/") {
+			t.Errorf("%s is not in the project directory", hdr.Name)
+		}
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := render.Dir(projectPath).WriteFile(hdr.Name, content); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dir := filepath.Join(projectPath, "archived")
+	for _, file := range []string{"go.mod", "go.sum", "migrations/00001_initial.sql"} {
+		if _, err := os.Stat(filepath.Join(dir, file)); err != nil {
+			t.Errorf("archive has no %s", file)
+		}
+	}
+
+	cmd := exec.Command("go", "build", "./...")
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("go build failed: %v\n%s", err, output)
+	}
+}
+
+// TestMigrateRouter migrates a project through every router, checking that
+// it builds after each migration and that local changes are kept.
+func TestMigrateRouter(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	projectPath := filepath.Join(t.TempDir(), "app")
+	e := NewEngine()
+	opts := modules.InitOptions{
+		ProjectName: "app",
+		ModulePath:  "example.com/app",
+		Router:      "chi",
+		Database:    "none",
+		OpenAPI:     "manual",
+		Migrations:  "none",
+	}
+	if err := e.InitProject(projectPath, opts); err != nil {
+		t.Fatalf("InitProject() error = %v", err)
+	}
+	for _, add := range []AddOptions{
+		{Module: "metrics"},
+		{Module: "auth", Type: "jwt"},
+		{Module: "rbac"},
+	} {
+		if err := e.AddModule(projectPath, add); err != nil {
+			t.Fatalf("AddModule(%+v) error = %v", add, err)
+		}
+	}
+	if err := e.GenerateContractTests(projectPath); err != nil {
+		t.Fatalf("GenerateContractTests() error = %v", err)
+	}
+
+	changed := map[string][]byte{}
+	for _, name := range []string{"server.go", "contract_test.go"} {
+		file := filepath.Join(projectPath, "internal", "http", name)
+		content, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		changed[file] = append(content, "\n// Local change\n"...)
+		if err := os.WriteFile(file, changed[file], 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, router := range []string{"gin", "fiber", "echo", "stdlib", "chi"} {
+		if err := e.MigrateRouter(projectPath, router); err != nil {
+			t.Fatalf("MigrateRouter(%s) error = %v", router, err)
+		}
+		project, err := e.detectProject(projectPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if project.Router != router {
+			t.Errorf("after MigrateRouter(%s) the project uses %s", router, project.Router)
+		}
+		for _, args := range [][]string{{"build", "./..."}, {"vet", "./..."}, {"test", "./..."}} {
+			cmd := exec.Command("go", args...)
+			cmd.Dir = projectPath
+			if output, err := cmd.CombinedOutput(); err != nil {
+				t.Errorf("go %s failed after migrating to %s: %s", strings.Join(args, " "), router, output)
+			}
+		}
+
+		if router == "gin" {
+			for file, content := range changed {
+				orig, err := os.ReadFile(file + ".orig")
+				if err != nil || string(orig) != string(content) {
+					t.Errorf("%s.orig = %q, %v; want the changed file", filepath.Base(file), orig, err)
+				}
+			}
+		}
+	}
+
+	if err := e.MigrateRouter(projectPath, "chi"); err == nil {
+		t.Error("MigrateRouter() to the project's router succeeded")
+	}
+}
+
+// TestContractTestsPass generates the spec and contract tests of a project
+// with each type of auth, and runs them against its server.
+func TestContractTestsPass(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	for _, authType := range []string{"jwt", "apikey", "oidc"} {
+		t.Run(authType, func(t *testing.T) {
+			projectPath := filepath.Join(t.TempDir(), "app")
+			e := NewEngine()
+			opts := modules.InitOptions{
+				ProjectName: "app",
+				ModulePath:  "example.com/app",
+				Router:      "chi",
+				Database:    "none",
+				OpenAPI:     "manual",
+				Migrations:  "none",
+			}
+			if err := e.InitProject(projectPath, opts); err != nil {
+				t.Fatalf("InitProject() error = %v", err)
+			}
+			if err := e.AddModule(projectPath, AddOptions{Module: "auth", Type: authType}); err != nil {
+				t.Fatalf("AddModule() error = %v", err)
+			}
+			if err := e.GenerateSpec(projectPath, GenerateOptions{Output: "api/openapi.yaml"}); err != nil {
+				t.Fatalf("GenerateSpec() error = %v", err)
+			}
+			if err := e.GenerateContractTests(projectPath); err != nil {
+				t.Fatalf("GenerateContractTests() error = %v", err)
+			}
+
+			cmd := exec.Command("go", "test", "-run", "Contract", "./internal/http")
+			cmd.Dir = projectPath
+			if output, err := cmd.CombinedOutput(); err != nil {
+				t.Errorf("contract tests failed: %s", output)
+			}
+		})
+	}
+}
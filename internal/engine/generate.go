@@ -0,0 +1,92 @@
+package engine
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/TRiZKy/gocrete/internal/modules"
+	"github.com/TRiZKy/gocrete/internal/render"
+)
+
+// Generate renders a new project into out: the base template and the
+// modules of opts. Unlike InitProject, it doesn't run the go command, so the
+// project has no go.mod or go.sum.
+func (e *Engine) Generate(out render.Output, opts modules.InitOptions) error {
+	if err := e.validateInitOptions(opts); err != nil {
+		return err
+	}
+	return e.generate(out, opts)
+}
+
+// generate renders a new project with valid options into out.
+func (e *Engine) generate(out render.Output, opts modules.InitOptions) error {
+	spec, err := readSpec(opts.SpecPath)
+	if err != nil {
+		return err
+	}
+
+	// Create context
+	ctx := &modules.Context{
+		Output:       out,
+		Progress:     e.Progress,
+		Options:      opts,
+		TemplateData: templateData(opts),
+		Spec:         spec,
+	}
+
+	// Apply base template
+	fmt.Fprintln(e.Progress, "→ Applying base template...")
+	if err := render.Apply(out, "files/base", ctx.TemplateData); err != nil {
+		return fmt.Errorf("failed to apply base template: %w", err)
+	}
+
+	// Apply database module
+	if opts.Database != "none" {
+		fmt.Fprintf(e.Progress, "→ Adding %s database...\n", opts.Database)
+		mod := e.registry.GetModule("db", opts.Database)
+		if mod == nil {
+			return fmt.Errorf("database module %s not found", opts.Database)
+		}
+		if err := mod.Apply(ctx); err != nil {
+			return fmt.Errorf("failed to apply database module: %w", err)
+		}
+	}
+
+	// Apply OpenAPI module
+	if opts.OpenAPI != "none" {
+		fmt.Fprintf(e.Progress, "→ Adding OpenAPI (%s mode)...\n", opts.OpenAPI)
+		mod := e.registry.GetModule("openapi", opts.OpenAPI)
+		if mod == nil {
+			return fmt.Errorf("openapi module %s not found", opts.OpenAPI)
+		}
+		if err := mod.Apply(ctx); err != nil {
+			return fmt.Errorf("failed to apply openapi module: %w", err)
+		}
+	}
+
+	// Apply Docker module
+	if opts.Docker {
+		fmt.Fprintln(e.Progress, "→ Adding Docker configuration...")
+		mod := e.registry.GetModule("docker", "")
+		if mod == nil {
+			return fmt.Errorf("docker module not found")
+		}
+		if err := mod.Apply(ctx); err != nil {
+			return fmt.Errorf("failed to apply docker module: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// readSpec reads the --spec file at path, if any.
+func readSpec(path string) ([]byte, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spec %s: %w", path, err)
+	}
+	return data, nil
+}
@@ -0,0 +1,83 @@
+package engine
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/TRiZKy/gocrete/internal/modules"
+	"github.com/TRiZKy/gocrete/internal/render"
+)
+
+func TestGenerate(t *testing.T) {
+	opts := modules.InitOptions{
+		ProjectName: "my-service",
+		ModulePath:  "github.com/test/my-service",
+		Router:      "gin",
+		Database:    "postgres",
+		OpenAPI:     "manual",
+		Migrations:  "goose",
+		Docker:      true,
+	}
+
+	out := render.Memory{}
+	if err := NewEngine().Generate(out, opts); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	for _, name := range []string{
+		"cmd/server/main.go",
+		"internal/http/server.go",
+		"internal/db/postgres/postgres.go",
+		"migrations/00001_initial.sql",
+		"api/openapi.yaml",
+		"Dockerfile",
+	} {
+		if _, ok := out[name]; !ok {
+			t.Errorf("Generate() did not write %s", name)
+		}
+	}
+	if _, ok := out["go.mod"]; ok {
+		t.Error("Generate() wrote go.mod, which is created by the go command")
+	}
+	if !bytes.Contains(out["internal/http/server.go"], []byte("gin.New()")) {
+		t.Errorf("server.go was not rendered for gin:\n%s", out["internal/http/server.go"])
+	}
+	if !bytes.Contains(out["api/openapi.yaml"], []byte("title: my-service API")) {
+		t.Errorf("openapi.yaml has no title:\n%s", out["api/openapi.yaml"])
+	}
+	if spec := out["api/openapi.yaml"]; !bytes.Contains(spec, []byte("/ready:")) || bytes.Contains(spec, []byte("/api/v1/users")) {
+		t.Errorf("openapi.yaml does not list the routes the server registers:\n%s", spec)
+	}
+}
+
+func TestGenerateInvalidOptions(t *testing.T) {
+	out := render.Memory{}
+	err := NewEngine().Generate(out, modules.InitOptions{
+		ProjectName: "test",
+		ModulePath:  "github.com/test/test",
+		Router:      "invalid",
+	})
+	if err == nil {
+		t.Fatal("Generate() expected error for an invalid router")
+	}
+	if len(out) != 0 {
+		t.Errorf("Generate() wrote %v for invalid options", out.Names())
+	}
+}
+
+func TestGenerateMissingSpec(t *testing.T) {
+	err := NewEngine().Generate(render.Memory{}, modules.InitOptions{
+		ProjectName: "test",
+		ModulePath:  "github.com/test/test",
+		Router:      "chi",
+		Database:    "none",
+		OpenAPI:     "gen",
+		SpecPath:    filepath.Join(t.TempDir(), "missing.yaml"),
+		Migrations:  "none",
+	})
+	if err == nil || !strings.Contains(err.Error(), "failed to read spec") {
+		t.Errorf("Generate() error = %v, want a spec read error", err)
+	}
+}
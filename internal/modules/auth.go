@@ -0,0 +1,109 @@
+package modules
+
+import (
+	"fmt"
+)
+
+// authCommonFiles are the files of files/auth/common, shared by the auth
+// modules. They are applied with ApplyTemplateFiles, as another auth module
+// may have written them already.
+var authCommonFiles = []string{
+	"internal/auth/jwks.go.tmpl",
+	"internal/auth/jwt.go.tmpl",
+	"internal/auth/jwt_test.go.tmpl",
+	"internal/auth/principal.go.tmpl",
+}
+
+type JWTAuthModule struct{}
+
+func (m *JWTAuthModule) Name() string {
+	return "auth-jwt"
+}
+
+func (m *JWTAuthModule) Apply(ctx *Context) error {
+	// Apply shared auth template (principal, token verification, JWKS)
+	if err := ApplyTemplateFiles(ctx, "files/auth/common", authCommonFiles...); err != nil {
+		return fmt.Errorf("failed to apply auth template: %w", err)
+	}
+
+	// Apply jwt template
+	templatePath := "files/auth/jwt"
+	if err := ApplyModuleTemplate(templatePath, ctx.Output, ctx.TemplateData); err != nil {
+		return fmt.Errorf("failed to apply jwt auth template: %w", err)
+	}
+
+	// Wire config, middleware and protected routes into the server
+	if err := ApplyBaseFiles(ctx, BaseWiringFiles...); err != nil {
+		return fmt.Errorf("failed to update base files: %w", err)
+	}
+
+	return applyAuthzRouteTests(ctx)
+}
+
+type OIDCAuthModule struct{}
+
+func (m *OIDCAuthModule) Name() string {
+	return "auth-oidc"
+}
+
+func (m *OIDCAuthModule) Apply(ctx *Context) error {
+	// Apply shared auth template (principal, token verification, JWKS)
+	if err := ApplyTemplateFiles(ctx, "files/auth/common", authCommonFiles...); err != nil {
+		return fmt.Errorf("failed to apply auth template: %w", err)
+	}
+
+	// Apply oidc template
+	templatePath := "files/auth/oidc"
+	if err := ApplyModuleTemplate(templatePath, ctx.Output, ctx.TemplateData); err != nil {
+		return fmt.Errorf("failed to apply oidc auth template: %w", err)
+	}
+
+	// Wire config, login routes and the protected example route into the server
+	if err := ApplyBaseFiles(ctx, BaseWiringFiles...); err != nil {
+		return fmt.Errorf("failed to update base files: %w", err)
+	}
+
+	return applyAuthzRouteTests(ctx)
+}
+
+type APIKeyAuthModule struct{}
+
+func (m *APIKeyAuthModule) Name() string {
+	return "auth-apikey"
+}
+
+func (m *APIKeyAuthModule) Apply(ctx *Context) error {
+	// Apply shared auth template (principal, token verification, JWKS)
+	if err := ApplyTemplateFiles(ctx, "files/auth/common", authCommonFiles...); err != nil {
+		return fmt.Errorf("failed to apply auth template: %w", err)
+	}
+
+	// Apply apikey template (key format, file store, middleware, admin CLI)
+	templatePath := "files/auth/apikey/base"
+	if err := ApplyModuleTemplate(templatePath, ctx.Output, ctx.TemplateData); err != nil {
+		return fmt.Errorf("failed to apply apikey auth template: %w", err)
+	}
+
+	// Store keys in the project's database when it has one
+	switch ctx.Options.Database {
+	case "postgres":
+		if err := ApplyModuleTemplate("files/auth/apikey/postgres", ctx.Output, ctx.TemplateData); err != nil {
+			return fmt.Errorf("failed to apply apikey postgres template: %w", err)
+		}
+
+		if err := addMigration(ctx, "api_keys"); err != nil {
+			return err
+		}
+	case "mongo":
+		if err := ApplyModuleTemplate("files/auth/apikey/mongo", ctx.Output, ctx.TemplateData); err != nil {
+			return fmt.Errorf("failed to apply apikey mongo template: %w", err)
+		}
+	}
+
+	// Wire the key store and the protected example route into the server
+	if err := ApplyBaseFiles(ctx, BaseWiringFiles...); err != nil {
+		return fmt.Errorf("failed to update base files: %w", err)
+	}
+
+	return nil
+}
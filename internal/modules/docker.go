@@ -13,7 +13,7 @@ func (m *DockerModule) Name() string {
 func (m *DockerModule) Apply(ctx *Context) error {
 	// Apply docker template
 	templatePath := "files/docker"
-	if err := ApplyModuleTemplate(templatePath, ctx.ProjectPath, ctx.TemplateData); err != nil {
+	if err := ApplyModuleTemplate(templatePath, ctx.Output, ctx.TemplateData); err != nil {
 		return fmt.Errorf("failed to apply docker template: %w", err)
 	}
 
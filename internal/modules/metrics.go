@@ -0,0 +1,42 @@
+package modules
+
+import (
+	"fmt"
+)
+
+type MetricsModule struct{}
+
+func (m *MetricsModule) Name() string {
+	return "metrics"
+}
+
+func (m *MetricsModule) Apply(ctx *Context) error {
+	// Apply metrics template
+	templatePath := "files/metrics/base"
+	if err := ApplyModuleTemplate(templatePath, ctx.Output, ctx.TemplateData); err != nil {
+		return fmt.Errorf("failed to apply metrics template: %w", err)
+	}
+
+	// Export connection pool statistics for the project's database
+	switch ctx.Options.Database {
+	case "postgres":
+		if err := ApplyModuleTemplate("files/metrics/postgres", ctx.Output, ctx.TemplateData); err != nil {
+			return fmt.Errorf("failed to apply metrics postgres template: %w", err)
+		}
+	case "mongo":
+		if err := ApplyModuleTemplate("files/metrics/mongo", ctx.Output, ctx.TemplateData); err != nil {
+			return fmt.Errorf("failed to apply metrics mongo template: %w", err)
+		}
+		// Older projects connect without accepting client options
+		if err := ApplyTemplateFiles(ctx, "files/db/mongo", "internal/db/mongo/mongo.go.tmpl"); err != nil {
+			return fmt.Errorf("failed to update mongo client: %w", err)
+		}
+	}
+
+	// Wire the middleware and the admin server
+	if err := ApplyBaseFiles(ctx, BaseWiringFiles...); err != nil {
+		return fmt.Errorf("failed to update base files: %w", err)
+	}
+
+	return nil
+}
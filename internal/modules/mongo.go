@@ -13,7 +13,7 @@ func (m *MongoModule) Name() string {
 func (m *MongoModule) Apply(ctx *Context) error {
 	// Apply mongo template
 	templatePath := "files/db/mongo"
-	if err := ApplyModuleTemplate(templatePath, ctx.ProjectPath, ctx.TemplateData); err != nil {
+	if err := ApplyModuleTemplate(templatePath, ctx.Output, ctx.TemplateData); err != nil {
 		return fmt.Errorf("failed to apply mongo template: %w", err)
 	}
 
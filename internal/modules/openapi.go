@@ -1,8 +1,12 @@
 package modules
 
 import (
+	"errors"
 	"fmt"
-	"path/filepath"
+	"io/fs"
+
+	"github.com/TRiZKy/gocrete/internal/codegen"
+	"github.com/TRiZKy/gocrete/internal/render"
 )
 
 type OpenAPIGenModule struct{}
@@ -12,68 +16,24 @@ func (m *OpenAPIGenModule) Name() string {
 }
 
 func (m *OpenAPIGenModule) Apply(ctx *Context) error {
-	// Apply openapi gen template
+	// Apply openapi gen template, with the Makefile generating the server
+	// interface
 	templatePath := "files/openapi/gen"
-	if err := ApplyModuleTemplate(templatePath, ctx.ProjectPath, ctx.TemplateData); err != nil {
+	if err := ApplyModuleTemplate(templatePath, ctx.Output, ctx.TemplateData); err != nil {
 		return fmt.Errorf("failed to apply openapi gen template: %w", err)
 	}
 
-	// Copy spec file if provided
-	if ctx.Options.SpecPath != "" {
-		// Create a simple example spec if file doesn't exist
-		specContent := `openapi: 3.0.0
-info:
-  title: ` + ctx.Options.ProjectName + ` API
-  version: 1.0.0
-paths:
-  /health:
-    get:
-      summary: Health check
-      responses:
-        '200':
-          description: Service is healthy
-          content:
-            application/json:
-              schema:
-                type: object
-                properties:
-                  status:
-                    type: string
-  /api/v1/users:
-    get:
-      summary: List users
-      responses:
-        '200':
-          description: List of users
-          content:
-            application/json:
-              schema:
-                type: array
-                items:
-                  type: object
-                  properties:
-                    id:
-                      type: integer
-                    email:
-                      type: string
-`
-		specPath := filepath.Join(ctx.ProjectPath, "api", "openapi.yaml")
-		if err := WriteFile(specPath, specContent); err != nil {
-			return err
-		}
+	// Embed and serve the spec, which drives request validation
+	err := applySpec(ctx, func() ([]byte, error) {
+		return render.File("files/openapi/specs", "gen.yaml.tmpl", ctx.TemplateData)
+	})
+	if err != nil {
+		return err
 	}
 
-	// Create Makefile for code generation
-	makefileContent := `.PHONY: generate
-generate:
-	go generate ./...
-
-.PHONY: api-gen
-api-gen:
-	oapi-codegen -package generated -generate types,chi-server,spec api/openapi.yaml > internal/api/generated/api.gen.go
-`
-	if err := WriteFile(filepath.Join(ctx.ProjectPath, "Makefile"), makefileContent); err != nil {
-		return err
+	// Wire the docs and the validation middleware
+	if err := ApplyBaseFiles(ctx, BaseWiringFiles...); err != nil {
+		return fmt.Errorf("failed to update base files: %w", err)
 	}
 
 	return nil
@@ -88,9 +48,47 @@ func (m *OpenAPIManualModule) Name() string {
 func (m *OpenAPIManualModule) Apply(ctx *Context) error {
 	// Apply openapi manual template
 	templatePath := "files/openapi/manual"
-	if err := ApplyModuleTemplate(templatePath, ctx.ProjectPath, ctx.TemplateData); err != nil {
+	if err := ApplyModuleTemplate(templatePath, ctx.Output, ctx.TemplateData); err != nil {
 		return fmt.Errorf("failed to apply openapi manual template: %w", err)
 	}
 
-	return nil
+	// Wire the docs
+	if err := ApplyBaseFiles(ctx, BaseWiringFiles...); err != nil {
+		return fmt.Errorf("failed to update base files: %w", err)
+	}
+
+	// Embed and serve the spec of the routes the server registers, as
+	// gocrete generate spec writes it
+	return applySpec(ctx, func() ([]byte, error) {
+		base := &codegen.Spec{}
+		base.Info.Title = ctx.Options.ProjectName + " API"
+		base.Info.Version = "1.0.0"
+		spec, err := codegen.ExtractSpecFS(ctx.Output, ctx.Options.ModulePath, base)
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract spec: %w", err)
+		}
+		return codegen.MarshalSpec(spec)
+	})
+}
+
+// applySpec writes api/openapi.yaml, which is embedded and served with the
+// docs. A spec already in the project is kept; otherwise the --spec file is
+// copied, or the spec returned by starter written without one.
+func applySpec(ctx *Context, starter func() ([]byte, error)) error {
+	if err := ApplyModuleTemplate("files/openapi/common", ctx.Output, ctx.TemplateData); err != nil {
+		return fmt.Errorf("failed to apply openapi common template: %w", err)
+	}
+
+	const specPath = "api/openapi.yaml"
+	if _, err := fs.Stat(ctx.Output, specPath); !errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if ctx.Spec != nil {
+		return ctx.Output.WriteFile(specPath, ctx.Spec)
+	}
+	spec, err := starter()
+	if err != nil {
+		return err
+	}
+	return ctx.Output.WriteFile(specPath, spec)
 }
@@ -0,0 +1,50 @@
+package modules
+
+import (
+	"fmt"
+)
+
+// retryFiles are the files of files/retry, shared by the outbox and worker
+// modules. They are applied with ApplyTemplateFiles, as the other module may
+// have written them already.
+var retryFiles = []string{
+	"internal/retry/backoff.go",
+	"internal/retry/backoff_test.go",
+}
+
+type OutboxModule struct{}
+
+func (m *OutboxModule) Name() string {
+	return "outbox"
+}
+
+func (m *OutboxModule) Apply(ctx *Context) error {
+	if ctx.Options.Database != "postgres" {
+		return fmt.Errorf("outbox module requires postgres, got database %q", ctx.Options.Database)
+	}
+
+	// Apply the retry package shared with the worker
+	if err := ApplyTemplateFiles(ctx, "files/retry", retryFiles...); err != nil {
+		return fmt.Errorf("failed to apply retry template: %w", err)
+	}
+
+	// Apply outbox template
+	templatePath := "files/outbox"
+	if err := ApplyModuleTemplate(templatePath, ctx.Output, ctx.TemplateData); err != nil {
+		return fmt.Errorf("failed to apply outbox template: %w", err)
+	}
+
+	// Add the outbox table migration after any existing ones
+	if err := addMigration(ctx, "outbox"); err != nil {
+		return err
+	}
+
+	// Rebuild Docker configuration so the relay binary is built and run
+	if ctx.Options.Docker {
+		if err := ApplyModuleTemplate("files/docker", ctx.Output, ctx.TemplateData); err != nil {
+			return fmt.Errorf("failed to update docker template: %w", err)
+		}
+	}
+
+	return nil
+}
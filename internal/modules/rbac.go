@@ -0,0 +1,46 @@
+package modules
+
+import (
+	"fmt"
+)
+
+type RBACModule struct{}
+
+func (m *RBACModule) Name() string {
+	return "rbac"
+}
+
+func (m *RBACModule) Apply(ctx *Context) error {
+	// Apply rbac template
+	templatePath := "files/rbac/base"
+	if err := ApplyModuleTemplate(templatePath, ctx.Output, ctx.TemplateData); err != nil {
+		return fmt.Errorf("failed to apply rbac template: %w", err)
+	}
+
+	// Let existing authentication middleware populate the authz principal
+	if len(ctx.Options.Auth) > 0 {
+		if err := ApplyTemplateFiles(ctx, "files/auth/common", "internal/auth/principal.go.tmpl"); err != nil {
+			return fmt.Errorf("failed to update auth template: %w", err)
+		}
+	}
+
+	// Wire the policy into config and the server
+	if err := ApplyBaseFiles(ctx, BaseWiringFiles...); err != nil {
+		return fmt.Errorf("failed to update base files: %w", err)
+	}
+
+	return applyAuthzRouteTests(ctx)
+}
+
+// applyAuthzRouteTests renders the tests of the routes protected by
+// authz.Require, which exist once the project has RBAC and a JWT or OIDC
+// module. Both the rbac and the auth modules apply it, whichever comes last.
+func applyAuthzRouteTests(ctx *Context) error {
+	if !ctx.Options.RBAC || !(ctx.Options.HasAuth("jwt") || ctx.Options.HasAuth("oidc")) {
+		return nil
+	}
+	if err := ApplyTemplateFiles(ctx, "files/rbac/http", "internal/http/authz_test.go.tmpl"); err != nil {
+		return fmt.Errorf("failed to apply rbac route tests: %w", err)
+	}
+	return nil
+}
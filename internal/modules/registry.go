@@ -1,13 +1,16 @@
 package modules
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"io/fs"
-	"os"
-	"path/filepath"
+	"path"
+	"regexp"
+	"strconv"
 	"strings"
-	"text/template"
 
+	"github.com/TRiZKy/gocrete/internal/render"
 	"github.com/TRiZKy/gocrete/pkg/templates"
 )
 
@@ -18,10 +21,19 @@ type Module interface {
 	Apply(ctx *Context) error
 }
 
+// Context is what modules are applied with. Modules write the project's
+// files, and read those it already has, through Output, and print their
+// progress to Progress. Spec is the content of the Options.SpecPath file,
+// read by the engine. When a module is added to a project, PreviousData is
+// the template data the project was generated with, to find the files
+// changed since.
 type Context struct {
-	ProjectPath  string
+	Output       render.Output
+	Progress     io.Writer
 	Options      InitOptions
 	TemplateData map[string]interface{}
+	PreviousData map[string]interface{}
+	Spec         []byte
 }
 
 type InitOptions struct {
@@ -34,6 +46,22 @@ type InitOptions struct {
 	Docker      bool
 	Migrations  string
 	Force       bool
+	Worker      bool
+	Outbox      bool
+	RBAC        bool
+	Metrics     bool
+	Tracing     bool
+	Auth        []string
+}
+
+// HasAuth reports whether the authentication method is enabled.
+func (o InitOptions) HasAuth(method string) bool {
+	for _, m := range o.Auth {
+		if m == method {
+			return true
+		}
+	}
+	return false
 }
 
 type Registry struct {
@@ -56,6 +84,20 @@ func NewRegistry() *Registry {
 	// Register Docker module
 	r.Register("docker", "", &DockerModule{})
 
+	// Register messaging and background processing modules
+	r.Register("outbox", "", &OutboxModule{})
+	r.Register("worker", "", &WorkerModule{})
+
+	// Register authentication modules
+	r.Register("auth", "jwt", &JWTAuthModule{})
+	r.Register("auth", "oidc", &OIDCAuthModule{})
+	r.Register("auth", "apikey", &APIKeyAuthModule{})
+	r.Register("rbac", "", &RBACModule{})
+
+	// Register observability modules
+	r.Register("metrics", "", &MetricsModule{})
+	r.Register("tracing", "", &TracingModule{})
+
 	return r
 }
 
@@ -75,70 +117,199 @@ func (r *Registry) GetModule(category, name string) Module {
 
 // Helper functions for modules
 
-func ApplyModuleTemplate(templatePath, destPath string, data map[string]interface{}) error {
-	return fs.WalkDir(templatesFS, templatePath, func(path string, d fs.DirEntry, err error) error {
+// BaseWiringFiles are the base template files whose content depends on the
+// enabled modules. Modules that need configuration, routes or startup code
+// re-render them with ApplyBaseFiles.
+var BaseWiringFiles = []string{
+	"cmd/server/main.go.tmpl",
+	"internal/config/config.go.tmpl",
+	"internal/config/loader.go",
+	"internal/errors/errors.go.tmpl",
+	"internal/errors/middleware.go.tmpl",
+	"internal/http/server.go.tmpl",
+	"internal/logger/logger.go",
+	"internal/logger/handlers.go",
+	".env.example.tmpl",
+}
+
+// ApplyBaseFiles renders the given files of the base template into the
+// project, as ApplyTemplateFiles.
+func ApplyBaseFiles(ctx *Context, files ...string) error {
+	return ApplyTemplateFiles(ctx, "files/base", files...)
+}
+
+// ApplyTemplateFiles renders the given files of templatePath into the
+// project. A file is only overwritten if it still has the content it was
+// generated with, rendered with ctx.PreviousData: a file changed since is
+// kept, and the new content written next to it with a .new suffix for the
+// user to merge.
+func ApplyTemplateFiles(ctx *Context, templatePath string, files ...string) error {
+	for _, file := range files {
+		name := strings.TrimSuffix(file, ".tmpl")
+		content, err := render.File(templatePath, file, ctx.TemplateData)
 		if err != nil {
 			return err
 		}
 
-		// Skip the root directory
-		if path == templatePath {
-			return nil
-		}
-
-		// Calculate relative path
-		relPath, err := filepath.Rel(templatePath, path)
+		changed, err := changedSinceGenerated(ctx, templatePath, file, content)
 		if err != nil {
 			return err
 		}
-
-		destFilePath := filepath.Join(destPath, relPath)
-
-		if d.IsDir() {
-			return os.MkdirAll(destFilePath, 0755)
+		if changed {
+			fmt.Fprintf(ctx.Progress, "⚠ Keeping %s, changed since it was generated: merge %s.new into it\n", name, path.Base(name))
+			name += ".new"
+		} else {
+			fmt.Fprintf(ctx.Progress, "→ Updating %s\n", name)
 		}
-
-		// Read file content
-		content, err := templatesFS.ReadFile(path)
-		if err != nil {
+		if err := ctx.Output.WriteFile(name, content); err != nil {
 			return err
 		}
+	}
 
-		// Check if file should be templated
-		if strings.HasSuffix(path, ".tmpl") {
-			destFilePath = strings.TrimSuffix(destFilePath, ".tmpl")
-			content, err = renderTemplate(string(content), data)
+	return nil
+}
+
+// changedSinceGenerated reports whether the project's copy of file differs
+// from both content and its render with ctx.PreviousData. Files of new
+// projects, without PreviousData, and missing files are never changed.
+func changedSinceGenerated(ctx *Context, templatePath, file string, content []byte) (bool, error) {
+	if ctx.PreviousData == nil {
+		return false, nil
+	}
+	current, err := fs.ReadFile(ctx.Output, strings.TrimSuffix(file, ".tmpl"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if render.SameSource(current, content) {
+		return false, nil
+	}
+	generated, err := render.File(templatePath, file, ctx.PreviousData)
+	if err != nil {
+		return false, err
+	}
+	return !render.SameSource(current, generated), nil
+}
+
+// routerTemplates are the directories of the base and module templates
+// rendered into projects that have files depending on the router.
+var routerTemplates = []string{
+	"files/base",
+	"files/auth/jwt",
+	"files/auth/oidc",
+	"files/auth/apikey/base",
+	"files/rbac/base",
+	"files/rbac/http",
+	"files/metrics/base",
+	"files/tracing",
+	"files/openapi/gen",
+}
+
+// RouterFile is a template file whose content depends on the router.
+type RouterFile struct {
+	// Template is the template directory of the file, and File its path
+	// in it.
+	Template string
+	File     string
+	// Path is the slash separated path of the file in projects.
+	Path string
+}
+
+// RouterFiles lists the files of the base and module templates that depend
+// on the router: the templates using .Router, .NetHTTP or the router
+// function.
+func RouterFiles() ([]RouterFile, error) {
+	var files []RouterFile
+	for _, templatePath := range routerTemplates {
+		err := fs.WalkDir(templatesFS, templatePath, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || !strings.HasSuffix(p, ".tmpl") {
+				return err
+			}
+			content, err := templatesFS.ReadFile(p)
 			if err != nil {
-				return fmt.Errorf("failed to render template %s: %w", path, err)
+				return err
 			}
+			if !routerPattern.Match(content) {
+				return nil
+			}
+			file := strings.TrimPrefix(p, templatePath+"/")
+			files = append(files, RouterFile{
+				Template: templatePath,
+				File:     file,
+				Path:     strings.TrimSuffix(file, ".tmpl"),
+			})
+			return nil
+		})
+		if err != nil {
+			return nil, err
 		}
+	}
+	return files, nil
+}
+
+// routerPattern matches the actions of templates depending on the router.
+var routerPattern = regexp.MustCompile(`\.(Router|NetHTTP)\b|\brouter "`)
 
-		// Write file
-		if err := os.MkdirAll(filepath.Dir(destFilePath), 0755); err != nil {
+// ApplyModuleTemplate renders the template directory templatePath into out.
+func ApplyModuleTemplate(templatePath string, out render.Output, data map[string]interface{}) error {
+	return render.Apply(out, templatePath, data)
+}
+
+// addMigration adds the migration name of files/migrations after the
+// project's existing migrations. Projects without migrations (--migrations
+// none) manage their schema themselves, so the migration is printed for
+// them to apply instead of creating a migrations directory.
+func addMigration(ctx *Context, name string) error {
+	if ctx.Options.Migrations != "goose" {
+		content, err := render.File("files/migrations", name+".sql.tmpl", ctx.TemplateData)
+		if err != nil {
 			return err
 		}
+		fmt.Fprintf(ctx.Progress, "⚠ The project has no migrations: apply the %s schema yourself\n\n%s\n", name, content)
+		return nil
+	}
 
-		return os.WriteFile(destFilePath, content, 0644)
-	})
+	version, err := nextMigrationVersion(ctx.Output)
+	if err != nil {
+		return err
+	}
+	return writeMigration(ctx, version, name)
 }
 
-func renderTemplate(content string, data map[string]interface{}) ([]byte, error) {
-	tmpl, err := template.New("template").Parse(content)
+// writeMigration renders the migration name of files/migrations into the
+// project's migrations directory as version_name.sql.
+func writeMigration(ctx *Context, version int, name string) error {
+	content, err := render.File("files/migrations", name+".sql.tmpl", ctx.TemplateData)
 	if err != nil {
-		return nil, err
+		return err
 	}
+	return ctx.Output.WriteFile(fmt.Sprintf("migrations/%05d_%s.sql", version, name), content)
+}
 
-	var buf strings.Builder
-	if err := tmpl.Execute(&buf, data); err != nil {
-		return nil, err
+// nextMigrationVersion returns the version number for a new migration in the
+// migrations directory of project, one past the highest numeric prefix of
+// the existing migration files.
+func nextMigrationVersion(project fs.FS) (int, error) {
+	entries, err := fs.ReadDir(project, "migrations")
+	if errors.Is(err, fs.ErrNotExist) {
+		return 1, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read migrations directory: %w", err)
 	}
 
-	return []byte(buf.String()), nil
-}
-
-func WriteFile(path, content string) error {
-	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
-		return err
+	highest := 0
+	for _, entry := range entries {
+		prefix, _, found := strings.Cut(entry.Name(), "_")
+		if entry.IsDir() || !found {
+			continue
+		}
+		if version, err := strconv.Atoi(prefix); err == nil && version > highest {
+			highest = version
+		}
 	}
-	return os.WriteFile(path, []byte(content), 0644)
+
+	return highest + 1, nil
 }
@@ -1,9 +1,13 @@
 package modules
 
 import (
-	"os"
-	"path/filepath"
+	"bytes"
+	"io"
+	"io/fs"
+	"strings"
 	"testing"
+
+	"github.com/TRiZKy/gocrete/internal/render"
 )
 
 func TestRegistryGetModule(t *testing.T) {
@@ -39,6 +43,54 @@ func TestRegistryGetModule(t *testing.T) {
 			modName:  "",
 			wantNil:  false,
 		},
+		{
+			name:     "outbox module exists",
+			category: "outbox",
+			modName:  "",
+			wantNil:  false,
+		},
+		{
+			name:     "worker module exists",
+			category: "worker",
+			modName:  "",
+			wantNil:  false,
+		},
+		{
+			name:     "jwt auth module exists",
+			category: "auth",
+			modName:  "jwt",
+			wantNil:  false,
+		},
+		{
+			name:     "oidc auth module exists",
+			category: "auth",
+			modName:  "oidc",
+			wantNil:  false,
+		},
+		{
+			name:     "apikey auth module exists",
+			category: "auth",
+			modName:  "apikey",
+			wantNil:  false,
+		},
+		{
+			name:     "rbac module exists",
+			category: "rbac",
+			modName:  "",
+			wantNil:  false,
+		},
+		{
+			name:     "metrics module exists",
+			category: "metrics",
+			modName:  "",
+			wantNil:  false,
+		},
+		{
+			name:     "tracing module exists",
+			category: "tracing",
+			modName:  "",
+			wantNil:  false,
+		},
 		{
 			name:     "non-existent module",
 			category: "db",
@@ -98,108 +150,259 @@ func TestDockerModuleName(t *testing.T) {
 	}
 }
 
-func TestWriteFile(t *testing.T) {
-	tmpDir := t.TempDir()
+func TestOutboxModuleName(t *testing.T) {
+	mod := &OutboxModule{}
+	if mod.Name() != "outbox" {
+		t.Errorf("OutboxModule.Name() = %v, want outbox", mod.Name())
+	}
+}
+
+func TestOutboxModuleRequiresPostgres(t *testing.T) {
+	ctx := &Context{
+		Output:       render.Memory{},
+		Options:      InitOptions{Database: "mongo"},
+		TemplateData: map[string]interface{}{},
+	}
 
-	testPath := filepath.Join(tmpDir, "subdir", "test.txt")
-	testContent := "test content"
+	mod := &OutboxModule{}
+	if err := mod.Apply(ctx); err == nil {
+		t.Error("OutboxModule.Apply() expected error for mongo project, got nil")
+	}
+}
 
-	err := WriteFile(testPath, testContent)
+func TestWorkerModuleName(t *testing.T) {
+	mod := &WorkerModule{}
+	if mod.Name() != "worker" {
+		t.Errorf("WorkerModule.Name() = %v, want worker", mod.Name())
+	}
+}
+
+func TestJWTAuthModuleName(t *testing.T) {
+	mod := &JWTAuthModule{}
+	if mod.Name() != "auth-jwt" {
+		t.Errorf("JWTAuthModule.Name() = %v, want auth-jwt", mod.Name())
+	}
+}
+
+func TestOIDCAuthModuleName(t *testing.T) {
+	mod := &OIDCAuthModule{}
+	if mod.Name() != "auth-oidc" {
+		t.Errorf("OIDCAuthModule.Name() = %v, want auth-oidc", mod.Name())
+	}
+}
+
+func TestAPIKeyAuthModuleName(t *testing.T) {
+	mod := &APIKeyAuthModule{}
+	if mod.Name() != "auth-apikey" {
+		t.Errorf("APIKeyAuthModule.Name() = %v, want auth-apikey", mod.Name())
+	}
+}
+
+func TestRBACModuleName(t *testing.T) {
+	mod := &RBACModule{}
+	if mod.Name() != "rbac" {
+		t.Errorf("RBACModule.Name() = %v, want rbac", mod.Name())
+	}
+}
+
+func TestMetricsModuleName(t *testing.T) {
+	mod := &MetricsModule{}
+	if mod.Name() != "metrics" {
+		t.Errorf("MetricsModule.Name() = %v, want metrics", mod.Name())
+	}
+}
+
+func TestTracingModuleName(t *testing.T) {
+	mod := &TracingModule{}
+	if mod.Name() != "tracing" {
+		t.Errorf("TracingModule.Name() = %v, want tracing", mod.Name())
+	}
+}
+
+func TestInitOptionsHasAuth(t *testing.T) {
+	opts := InitOptions{Auth: []string{"jwt"}}
+	if !opts.HasAuth("jwt") {
+		t.Error("HasAuth(jwt) = false, want true")
+	}
+	if opts.HasAuth("apikey") {
+		t.Error("HasAuth(apikey) = true, want false")
+	}
+}
+
+func TestNextMigrationVersion(t *testing.T) {
+	project := render.Memory{}
+
+	version, err := nextMigrationVersion(project)
 	if err != nil {
-		t.Fatalf("WriteFile() error = %v", err)
+		t.Fatalf("nextMigrationVersion() error = %v", err)
+	}
+	if version != 1 {
+		t.Errorf("nextMigrationVersion() on missing dir = %d, want 1", version)
 	}
 
-	// Verify file exists
-	if _, err := os.Stat(testPath); os.IsNotExist(err) {
-		t.Error("WriteFile() did not create file")
+	for _, name := range []string{"00001_initial.sql", "00007_orders.sql", ".gitkeep", "README.md"} {
+		if err := project.WriteFile("migrations/"+name, nil); err != nil {
+			t.Fatal(err)
+		}
 	}
 
-	// Verify content
-	content, err := os.ReadFile(testPath)
+	version, err = nextMigrationVersion(project)
 	if err != nil {
-		t.Fatalf("failed to read file: %v", err)
+		t.Fatalf("nextMigrationVersion() error = %v", err)
 	}
+	if version != 8 {
+		t.Errorf("nextMigrationVersion() = %d, want 8", version)
+	}
+}
 
-	if string(content) != testContent {
-		t.Errorf("file content = %v, want %v", string(content), testContent)
+func TestWriteMigration(t *testing.T) {
+	project := render.Memory{}
+	ctx := &Context{Output: project, TemplateData: map[string]interface{}{}}
+
+	if err := writeMigration(ctx, 3, "outbox"); err != nil {
+		t.Fatalf("writeMigration() error = %v", err)
+	}
+
+	content, ok := project["migrations/00003_outbox.sql"]
+	if !ok {
+		t.Fatalf("writeMigration() wrote %v", project.Names())
+	}
+	for _, want := range []string{
+		"-- +goose Up\n-- +goose StatementBegin\nCREATE TABLE IF NOT EXISTS outbox (",
+		"-- +goose Down\n-- +goose StatementBegin\nDROP TABLE IF EXISTS outbox;\n-- +goose StatementEnd\n",
+	} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("migration = %q, want it to contain %q", content, want)
+		}
 	}
 }
 
-func TestRenderTemplate(t *testing.T) {
+func TestAddMigration(t *testing.T) {
 	tests := []struct {
-		name     string
-		template string
-		data     map[string]interface{}
-		want     string
-		wantErr  bool
+		migrations string
+		wantFile   string
 	}{
-		{
-			name:     "simple substitution",
-			template: "module {{.ModulePath}}",
-			data:     map[string]interface{}{"ModulePath": "github.com/test/project"},
-			want:     "module github.com/test/project",
-			wantErr:  false,
-		},
-		{
-			name: "conditional - true",
-			template: `{{if eq .Database "postgres"}}
-DATABASE_URL=postgres://localhost:5432/db
-{{end}}`,
-			data:    map[string]interface{}{"Database": "postgres"},
-			want:    "\nDATABASE_URL=postgres://localhost:5432/db\n",
-			wantErr: false,
-		},
-		{
-			name: "conditional - false",
-			template: `{{if eq .Database "postgres"}}
-DATABASE_URL=postgres://localhost:5432/db
-{{end}}`,
-			data:    map[string]interface{}{"Database": "none"},
-			want:    "",
-			wantErr: false,
-		},
+		{migrations: "goose", wantFile: "migrations/00002_outbox.sql"},
+		{migrations: "none"},
 	}
 
 	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			got, err := renderTemplate(tt.template, tt.data)
-			if (err != nil) != tt.wantErr {
-				t.Errorf("renderTemplate() error = %v, wantErr %v", err, tt.wantErr)
+		t.Run(tt.migrations, func(t *testing.T) {
+			project := render.Memory{}
+			if tt.migrations == "goose" {
+				project["migrations/00001_initial.sql"] = nil
+			}
+			var progress bytes.Buffer
+			ctx := &Context{
+				Output:       project,
+				Options:      InitOptions{Migrations: tt.migrations},
+				TemplateData: map[string]interface{}{},
+				Progress:     &progress,
+			}
+
+			if err := addMigration(ctx, "outbox"); err != nil {
+				t.Fatalf("addMigration() error = %v", err)
+			}
+
+			if tt.wantFile != "" {
+				if _, ok := project[tt.wantFile]; !ok {
+					t.Errorf("addMigration() wrote %v, want %s", project.Names(), tt.wantFile)
+				}
 				return
 			}
-			if !tt.wantErr && string(got) != tt.want {
-				t.Errorf("renderTemplate() = %q, want %q", string(got), tt.want)
+			if len(project) != 0 {
+				t.Errorf("addMigration() wrote %v without migrations", project.Names())
+			}
+			if !strings.Contains(progress.String(), "CREATE TABLE IF NOT EXISTS outbox (") {
+				t.Errorf("progress = %q, want the migration", progress.String())
 			}
 		})
 	}
 }
 
-// Benchmark tests
-func BenchmarkRenderTemplate(b *testing.B) {
-	template := "module {{.ModulePath}}\n{{if eq .Database \"postgres\"}}DATABASE_URL=postgres://localhost{{end}}"
-	data := map[string]interface{}{
-		"ModulePath": "github.com/test/project",
-		"Database":   "postgres",
-	}
-
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		_, err := renderTemplate(template, data)
-		if err != nil {
-			b.Fatal(err)
+func TestRouterFiles(t *testing.T) {
+	files, err := RouterFiles()
+	if err != nil {
+		t.Fatalf("RouterFiles() error = %v", err)
+	}
+	paths := map[string]bool{}
+	for _, f := range files {
+		paths[f.Path] = true
+	}
+
+	for _, want := range []string{"cmd/server/main.go", "internal/http/server.go", "internal/auth/jwt_middleware.go", "internal/api/openapi/middleware.go"} {
+		if !paths[want] {
+			t.Errorf("RouterFiles() is missing %s", want)
 		}
 	}
+	if paths["internal/config/config.go"] {
+		t.Error("RouterFiles() lists internal/config/config.go, which doesn't depend on the router")
+	}
 }
 
-func BenchmarkWriteFile(b *testing.B) {
-	tmpDir := b.TempDir()
-	content := "test content"
+func TestApplyTemplateFiles(t *testing.T) {
+	const file = "internal/auth/principal.go"
+	previous := map[string]interface{}{"ModulePath": "example.com/app", "HasRBAC": false}
+	data := map[string]interface{}{"ModulePath": "example.com/app", "HasRBAC": true}
+	generated, err := render.File("files/auth/common", file+".tmpl", previous)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := render.File("files/auth/common", file+".tmpl", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		current []byte
+		want    []byte
+		wantNew bool
+	}{
+		{name: "missing", want: want},
+		{name: "unchanged", current: generated, want: want},
+		{name: "changed", current: append(generated, "\n// Local change\n"...), want: append(generated, "\n// Local change\n"...), wantNew: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			project := render.Memory{}
+			if tt.current != nil {
+				project[file] = tt.current
+			}
+			ctx := &Context{Output: project, Progress: io.Discard, TemplateData: data, PreviousData: previous}
+			if err := ApplyTemplateFiles(ctx, "files/auth/common", file+".tmpl"); err != nil {
+				t.Fatalf("ApplyTemplateFiles() error = %v", err)
+			}
+
+			if string(project[file]) != string(tt.want) {
+				t.Errorf("%s = %q, want %q", file, project[file], tt.want)
+			}
+			newContent, ok := project[file+".new"]
+			if ok != tt.wantNew {
+				t.Fatalf("%s.new written = %v, want %v", file, ok, tt.wantNew)
+			}
+			if ok && string(newContent) != string(want) {
+				t.Errorf("%s.new = %q, want %q", file, newContent, want)
+			}
+		})
+	}
+}
 
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		path := filepath.Join(tmpDir, "test", "file", "path", "file.txt")
-		if err := WriteFile(path, content); err != nil {
-			b.Fatal(err)
+func TestAuthCommonFiles(t *testing.T) {
+	var files []string
+	err := fs.WalkDir(templatesFS, "files/auth/common", func(p string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			files = append(files, strings.TrimPrefix(p, "files/auth/common/"))
 		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(files, " ") != strings.Join(authCommonFiles, " ") {
+		t.Errorf("authCommonFiles = %v, want the files of files/auth/common: %v", authCommonFiles, files)
 	}
 }
+
+// Benchmark tests
@@ -0,0 +1,38 @@
+package modules
+
+import (
+	"fmt"
+)
+
+type TracingModule struct{}
+
+func (m *TracingModule) Name() string {
+	return "tracing"
+}
+
+func (m *TracingModule) Apply(ctx *Context) error {
+	// Apply tracing template
+	templatePath := "files/tracing"
+	if err := ApplyModuleTemplate(templatePath, ctx.Output, ctx.TemplateData); err != nil {
+		return fmt.Errorf("failed to apply tracing template: %w", err)
+	}
+
+	// Instrument the project's database client
+	switch ctx.Options.Database {
+	case "postgres":
+		if err := ApplyTemplateFiles(ctx, "files/db/postgres", "internal/db/postgres/postgres.go.tmpl"); err != nil {
+			return fmt.Errorf("failed to update postgres client: %w", err)
+		}
+	case "mongo":
+		if err := ApplyTemplateFiles(ctx, "files/db/mongo", "internal/db/mongo/mongo.go.tmpl"); err != nil {
+			return fmt.Errorf("failed to update mongo client: %w", err)
+		}
+	}
+
+	// Wire the middleware, the logger and span flushing on shutdown
+	if err := ApplyBaseFiles(ctx, BaseWiringFiles...); err != nil {
+		return fmt.Errorf("failed to update base files: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,44 @@
+package modules
+
+import (
+	"fmt"
+)
+
+type WorkerModule struct{}
+
+func (m *WorkerModule) Name() string {
+	return "worker"
+}
+
+func (m *WorkerModule) Apply(ctx *Context) error {
+	// Apply the retry package shared with the outbox
+	if err := ApplyTemplateFiles(ctx, "files/retry", retryFiles...); err != nil {
+		return fmt.Errorf("failed to apply retry template: %w", err)
+	}
+
+	// Apply worker template
+	templatePath := "files/worker/base"
+	if err := ApplyModuleTemplate(templatePath, ctx.Output, ctx.TemplateData); err != nil {
+		return fmt.Errorf("failed to apply worker template: %w", err)
+	}
+
+	// Back the job queue with Postgres when available
+	if ctx.Options.Database == "postgres" {
+		if err := ApplyModuleTemplate("files/worker/postgres", ctx.Output, ctx.TemplateData); err != nil {
+			return fmt.Errorf("failed to apply worker postgres template: %w", err)
+		}
+
+		if err := addMigration(ctx, "jobs"); err != nil {
+			return err
+		}
+	}
+
+	// Rebuild Docker configuration so the worker binary is built and run
+	if ctx.Options.Docker {
+		if err := ApplyModuleTemplate("files/docker", ctx.Output, ctx.TemplateData); err != nil {
+			return fmt.Errorf("failed to update docker template: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,142 @@
+package render
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing/fstest"
+	"time"
+)
+
+// Output receives the files of a project. Files are named by their slash
+// separated path in the project, and can be read back through the fs.FS, as
+// modules check what the project already has.
+type Output interface {
+	fs.FS
+	WriteFile(name string, data []byte) error
+}
+
+// Dir is an Output writing to a directory of the OS.
+type Dir string
+
+func (d Dir) Open(name string) (fs.File, error) {
+	return os.DirFS(string(d)).Open(name)
+}
+
+func (d Dir) WriteFile(name string, data []byte) error {
+	path := filepath.Join(string(d), filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Memory is an Output keeping the files in memory, by path.
+type Memory map[string][]byte
+
+func (m Memory) Open(name string) (fs.File, error) {
+	files := make(fstest.MapFS, len(m))
+	for name, data := range m {
+		files[name] = &fstest.MapFile{Data: data, Mode: 0644}
+	}
+	return files.Open(name)
+}
+
+func (m Memory) WriteFile(name string, data []byte) error {
+	if !fs.ValidPath(name) {
+		return &fs.PathError{Op: "write", Path: name, Err: fs.ErrInvalid}
+	}
+	m[name] = append([]byte(nil), data...)
+	return nil
+}
+
+// Names returns the paths of the files, sorted.
+func (m Memory) Names() []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Archive formats.
+const (
+	Tar = "tar"
+	Zip = "zip"
+)
+
+// modTime is the time of the files of archives, so that the same files
+// always give the same archive.
+var modTime = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Archive is an Output streaming the files as a tar or zip archive once
+// closed. The files are kept in memory until then, and written in path order
+// with a fixed time and mode, so that the same files give the same bytes.
+type Archive struct {
+	Memory
+	w      io.Writer
+	format string
+}
+
+// NewArchive returns an Archive writing to w in format, Tar or Zip.
+func NewArchive(w io.Writer, format string) (*Archive, error) {
+	if format != Tar && format != Zip {
+		return nil, fmt.Errorf("invalid archive format: %s (must be %s or %s)", format, Tar, Zip)
+	}
+	return &Archive{Memory: Memory{}, w: w, format: format}, nil
+}
+
+// Close writes the archive.
+func (a *Archive) Close() error {
+	if a.format == Zip {
+		return a.writeZip()
+	}
+	return a.writeTar()
+}
+
+func (a *Archive) writeTar() error {
+	tw := tar.NewWriter(a.w)
+	for _, name := range a.Names() {
+		data := a.Memory[name]
+		hdr := &tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     name,
+			Size:     int64(len(data)),
+			Mode:     0644,
+			ModTime:  modTime,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+func (a *Archive) writeZip() error {
+	zw := zip.NewWriter(a.w)
+	for _, name := range a.Names() {
+		hdr := &zip.FileHeader{
+			Name:     name,
+			Method:   zip.Deflate,
+			Modified: modTime,
+		}
+		hdr.SetMode(0644)
+		w, err := zw.CreateHeader(hdr)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(a.Memory[name]); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
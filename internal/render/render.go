@@ -0,0 +1,95 @@
+// Package render renders the embedded templates of pkg/templates into an
+// Output: a directory, memory or an archive stream.
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/TRiZKy/gocrete/internal/routers"
+	"github.com/TRiZKy/gocrete/pkg/templates"
+)
+
+// Apply renders the files of the template directory templatePath into out.
+// Files ending in .tmpl are rendered with data and written without the
+// suffix; the others are copied as-is.
+func Apply(out Output, templatePath string, data map[string]interface{}) error {
+	return fs.WalkDir(templates.FS, templatePath, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		file := strings.TrimPrefix(p, templatePath+"/")
+		content, err := File(templatePath, file, data)
+		if err != nil {
+			return err
+		}
+		return out.WriteFile(strings.TrimSuffix(file, ".tmpl"), content)
+	})
+}
+
+// Copy writes the files of src into out, under the directory dir.
+func Copy(out Output, src fs.FS, dir string) error {
+	return fs.WalkDir(src, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		content, err := fs.ReadFile(src, p)
+		if err != nil {
+			return err
+		}
+		return out.WriteFile(path.Join(dir, p), content)
+	})
+}
+
+// File returns the content of file of templatePath, rendered with data if
+// it is a template.
+func File(templatePath, file string, data map[string]interface{}) ([]byte, error) {
+	content, err := templates.FS.ReadFile(path.Join(templatePath, file))
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(file, ".tmpl") {
+		return content, nil
+	}
+
+	content, err = Template(string(content), data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render template %s: %w", path.Join(templatePath, file), err)
+	}
+	return content, nil
+}
+
+// Template renders content with data, the shared template functions and
+// partials, and the router function of the router of data.
+func Template(content string, data map[string]interface{}) ([]byte, error) {
+	router, _ := data["Router"].(string)
+	tmpl, err := templates.Parse("template", content, routers.Funcs(router))
+	if err != nil {
+		return nil, err
+	}
+
+	var buf strings.Builder
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+
+	return []byte(buf.String()), nil
+}
+
+// SameSource reports whether a project file has the content it was
+// generated with, ignoring formatting, which go fmt changes after rendering.
+func SameSource(current, generated []byte) bool {
+	if formatted, err := format.Source(generated); err == nil {
+		generated = formatted
+	}
+	if formatted, err := format.Source(current); err == nil {
+		current = formatted
+	}
+	return bytes.Equal(current, generated)
+}
@@ -0,0 +1,286 @@
+package render
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		data     map[string]interface{}
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "simple substitution",
+			template: "module {{.ModulePath}}",
+			data:     map[string]interface{}{"ModulePath": "github.com/test/project"},
+			want:     "module github.com/test/project",
+			wantErr:  false,
+		},
+		{
+			name: "conditional - true",
+			template: `{{if eq .Database "postgres"}}
+DATABASE_URL=postgres://localhost:5432/db
+{{end}}`,
+			data:    map[string]interface{}{"Database": "postgres"},
+			want:    "\nDATABASE_URL=postgres://localhost:5432/db\n",
+			wantErr: false,
+		},
+		{
+			name: "conditional - false",
+			template: `{{if eq .Database "postgres"}}
+DATABASE_URL=postgres://localhost:5432/db
+{{end}}`,
+			data:    map[string]interface{}{"Database": "none"},
+			want:    "",
+			wantErr: false,
+		},
+		{
+			name:     "functions",
+			template: `{{.ProjectName | pascal}} {{.Router | default "chi"}}`,
+			data:     map[string]interface{}{"ProjectName": "my-service"},
+			want:     "MyService chi",
+			wantErr:  false,
+		},
+		{
+			name:     "required",
+			template: `{{required "module path is required" .ModulePath}}`,
+			data:     map[string]interface{}{},
+			wantErr:  true,
+		},
+		{
+			name:     "invalid template",
+			template: "{{.InvalidSyntax",
+			data:     map[string]interface{}{},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Template(tt.template, tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Template() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && string(got) != tt.want {
+				t.Errorf("Template() = %q, want %q", string(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	out := Memory{}
+	data := map[string]interface{}{
+		"ProjectName": "my-service",
+		"ModulePath":  "github.com/test/my-service",
+		"Router":      "chi",
+		"NetHTTP":     true,
+		"Database":    "none",
+		"OpenAPI":     "none",
+	}
+
+	if err := Apply(out, "files/base", data); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+
+	server, ok := out["internal/http/server.go"]
+	if !ok {
+		t.Fatalf("Apply() wrote %v, want internal/http/server.go", out.Names())
+	}
+	if !bytes.Contains(server, []byte("chi.NewRouter()")) {
+		t.Errorf("server.go was not rendered for chi:\n%s", server)
+	}
+	for _, name := range out.Names() {
+		if strings.HasSuffix(name, ".tmpl") {
+			t.Errorf("Apply() wrote %s with its template suffix", name)
+		}
+	}
+}
+
+func TestCopy(t *testing.T) {
+	src := Memory{}
+	for _, name := range []string{"go.mod", "internal/http/server.go"} {
+		if err := src.WriteFile(name, []byte(name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out := Memory{}
+	if err := Copy(out, src, "app"); err != nil {
+		t.Fatalf("Copy() error = %v", err)
+	}
+	if got := strings.Join(out.Names(), ","); got != "app/go.mod,app/internal/http/server.go" {
+		t.Errorf("Copy() wrote %s", got)
+	}
+	if string(out["app/go.mod"]) != "go.mod" {
+		t.Errorf("app/go.mod = %q", out["app/go.mod"])
+	}
+}
+
+func TestDir(t *testing.T) {
+	tmpDir := t.TempDir()
+	out := Dir(tmpDir)
+
+	if err := out.WriteFile("subdir/test.txt", []byte("test content")); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(tmpDir, "subdir", "test.txt"))
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+	if string(content) != "test content" {
+		t.Errorf("file content = %q, want %q", content, "test content")
+	}
+
+	content, err = fs.ReadFile(out, "subdir/test.txt")
+	if err != nil || string(content) != "test content" {
+		t.Errorf("ReadFile() = %q, %v", content, err)
+	}
+}
+
+func TestMemory(t *testing.T) {
+	out := Memory{}
+	for _, name := range []string{"migrations/00002_b.sql", "migrations/00001_a.sql", "go.mod"} {
+		if err := out.WriteFile(name, []byte(name)); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+	if err := out.WriteFile("../escape", nil); err == nil {
+		t.Error("WriteFile() of a path outside the project succeeded")
+	}
+
+	if got := strings.Join(out.Names(), ","); got != "go.mod,migrations/00001_a.sql,migrations/00002_b.sql" {
+		t.Errorf("Names() = %s", got)
+	}
+
+	entries, err := fs.ReadDir(out, "migrations")
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("ReadDir() = %v, %v", entries, err)
+	}
+	if _, err := fs.Stat(out, "api/openapi.yaml"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Stat() of a missing file error = %v, want fs.ErrNotExist", err)
+	}
+}
+
+func TestArchive(t *testing.T) {
+	files := map[string]string{
+		"go.mod":                  "module example.com/app\n",
+		"cmd/server/main.go":      "package main\n",
+		"internal/http/server.go": "package http\n",
+	}
+
+	for _, format := range []string{Tar, Zip} {
+		t.Run(format, func(t *testing.T) {
+			write := func() []byte {
+				var buf bytes.Buffer
+				out, err := NewArchive(&buf, format)
+				if err != nil {
+					t.Fatalf("NewArchive() error = %v", err)
+				}
+				for name, content := range files {
+					if err := out.WriteFile(name, []byte(content)); err != nil {
+						t.Fatalf("WriteFile() error = %v", err)
+					}
+				}
+				if err := out.Close(); err != nil {
+					t.Fatalf("Close() error = %v", err)
+				}
+				return buf.Bytes()
+			}
+
+			archive := write()
+			if !bytes.Equal(archive, write()) {
+				t.Error("archives of the same files differ")
+			}
+
+			got := map[string]string{}
+			var names []string
+			switch format {
+			case Tar:
+				tr := tar.NewReader(bytes.NewReader(archive))
+				for {
+					hdr, err := tr.Next()
+					if err == io.EOF {
+						break
+					}
+					if err != nil {
+						t.Fatalf("reading tar: %v", err)
+					}
+					content, _ := io.ReadAll(tr)
+					got[hdr.Name] = string(content)
+					names = append(names, hdr.Name)
+				}
+			case Zip:
+				zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+				if err != nil {
+					t.Fatalf("reading zip: %v", err)
+				}
+				for _, f := range zr.File {
+					rc, err := f.Open()
+					if err != nil {
+						t.Fatalf("opening %s: %v", f.Name, err)
+					}
+					content, _ := io.ReadAll(rc)
+					rc.Close()
+					got[f.Name] = string(content)
+					names = append(names, f.Name)
+				}
+			}
+
+			if strings.Join(names, ",") != "cmd/server/main.go,go.mod,internal/http/server.go" {
+				t.Errorf("archive files = %v, want them in path order", names)
+			}
+			for name, content := range files {
+				if got[name] != content {
+					t.Errorf("%s = %q, want %q", name, got[name], content)
+				}
+			}
+		})
+	}
+
+	if _, err := NewArchive(io.Discard, "rar"); err == nil {
+		t.Error("NewArchive() of an unknown format succeeded")
+	}
+}
+
+func BenchmarkTemplate(b *testing.B) {
+	template := "module {{.ModulePath}}\n{{if eq .Database \"postgres\"}}DATABASE_URL=postgres://localhost{{end}}"
+	data := map[string]interface{}{
+		"ModulePath": "github.com/test/project",
+		"Database":   "postgres",
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := Template(template, data)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDirWriteFile(b *testing.B) {
+	out := Dir(b.TempDir())
+	content := []byte("test content")
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := out.WriteFile("test/file/path/file.txt", content); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+// Package routers holds the HTTP routers gocrete generates servers for.
+//
+// Each router is an Adapter: what gocrete needs to know about it, and the
+// partials rendering the generated code that differs between routers. The
+// partials of a router are the {{define}} blocks of the templates in
+// pkg/templates/files/routers/<name>, and in the directories of routers it
+// shares with others: nethttp for the routers whose handlers are net/http
+// ones, httpserver for those served by an http.Server of main. The
+// templates of pkg/templates/files/routers itself are shared by every
+// router: the empty defaults of defaults.tmpl, and partials written once
+// for all, such as the routes of server.routes. A router's own partials
+// replace the shared ones of the same name. Templates render the partial of
+// the project's router with the router function:
+//
+//	{{- router "server.field" .}}
+//
+// Partials of whole lines start with a newline and end without one, so they
+// are called with a trimming action. Module templates hold no code of their
+// own for a router: what differs is in partials named after the module,
+// such as metrics.middleware in routers/<name>/metrics.go.tmpl. Every router
+// defines:
+//
+//	import             the import of its package, for module templates
+//	route              a GET route, from route "path" "handler" "middleware"...
+//	route.handler      a GET route served by an http.Handler
+//	route.prefix       a GET route of the paths under a prefix, served by an http.Handler
+//	server.stdimports  the standard library imports of internal/http/server.go
+//	server.imports     its imports in internal/http/server.go
+//	server.field       the router field of Server
+//	server.setup       setupRouter, registering the middleware and routes, and its handlers
+//	main.serve         starting the server in cmd/server/main.go
+//	main.shutdown      shutting it down once ctx, the shutdown context, is set
+//
+// Adding a router means registering an Adapter in Adapters and writing its
+// partials.
+package routers
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/TRiZKy/gocrete/pkg/templates"
+)
+
+// Default is the router of projects that don't choose one.
+const Default = "chi"
+
+// Adapter describes a router.
+type Adapter struct {
+	// Name is the value of --router.
+	Name string
+	// Module is the Go module generated projects require for the router, or
+	// "" for the standard library.
+	Module string
+	// NetHTTP is set for the routers whose handlers and middleware are plain
+	// net/http ones.
+	NetHTTP bool
+	// OpenAPIServer is the oapi-codegen generator of the server interface.
+	OpenAPIServer string
+	// Shared lists the directories of partials shared with other routers.
+	Shared []string
+
+	once     sync.Once
+	partials *template.Template
+	err      error
+}
+
+// Adapters are the supported routers, in the order they are listed to users.
+var Adapters = []*Adapter{
+	{
+		Name:          "chi",
+		Module:        "github.com/go-chi/chi/v5",
+		NetHTTP:       true,
+		OpenAPIServer: "chi-server",
+		Shared:        []string{"nethttp", "httpserver"},
+	},
+	{
+		Name:          "gin",
+		Module:        "github.com/gin-gonic/gin",
+		OpenAPIServer: "gin-server",
+		Shared:        []string{"httpserver"},
+	},
+	{
+		Name:          "fiber",
+		Module:        "github.com/gofiber/fiber/v2",
+		OpenAPIServer: "fiber-server",
+	},
+	{
+		Name:          "echo",
+		Module:        "github.com/labstack/echo/v4",
+		OpenAPIServer: "echo-server",
+	},
+	{
+		Name:          "stdlib",
+		NetHTTP:       true,
+		OpenAPIServer: "std-http-server",
+		Shared:        []string{"nethttp", "httpserver"},
+	},
+}
+
+// Get returns the router named name, or nil.
+func Get(name string) *Adapter {
+	for _, a := range Adapters {
+		if a.Name == name {
+			return a
+		}
+	}
+	return nil
+}
+
+// Names returns the names of the routers.
+func Names() []string {
+	names := make([]string, len(Adapters))
+	for i, a := range Adapters {
+		names[i] = a.Name
+	}
+	return names
+}
+
+// Detect returns the router of a project from the modules it requires. The
+// default router is only chosen when no other router's module is required,
+// as other dependencies may require it; the standard library is chosen when
+// none is.
+func Detect(requires func(module string) bool) *Adapter {
+	var found *Adapter
+	for _, a := range Adapters {
+		switch {
+		case a.Module == "":
+			if found == nil {
+				found = a
+			}
+		case !requires(a.Module):
+		case a.Name != Default:
+			return a
+		default:
+			found = a
+		}
+	}
+	return found
+}
+
+// Funcs returns the template functions rendering the partials of the router
+// named router.
+func Funcs(router string) template.FuncMap {
+	return template.FuncMap{
+		"router": func(name string, data interface{}) (string, error) {
+			a := Get(router)
+			if a == nil {
+				return "", fmt.Errorf("unknown router %q", router)
+			}
+			return a.Render(name, data)
+		},
+	}
+}
+
+// Render renders the partial name with data.
+func (a *Adapter) Render(name string, data interface{}) (string, error) {
+	partials, err := a.load()
+	if err != nil {
+		return "", err
+	}
+	if partials.Lookup(name) == nil {
+		return "", fmt.Errorf("router %s has no partial %q", a.Name, name)
+	}
+
+	var buf bytes.Buffer
+	if err := partials.ExecuteTemplate(&buf, name, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// Defines reports whether the router has the partial name.
+func (a *Adapter) Defines(name string) bool {
+	partials, err := a.load()
+	return err == nil && partials.Lookup(name) != nil
+}
+
+// load parses the partials of the router once.
+func (a *Adapter) load() (*template.Template, error) {
+	a.once.Do(func() {
+		// Partials parsed later replace those of the same name
+		patterns := []string{"files/routers/*.tmpl"}
+		for _, dir := range append(append([]string{}, a.Shared...), a.Name) {
+			patterns = append(patterns, path.Join("files/routers", dir, "*.tmpl"))
+		}
+		a.partials, a.err = template.New(a.Name).Funcs(templates.Funcs()).Funcs(Funcs(a.Name)).
+			Funcs(template.FuncMap{"route": newRoute}).ParseFS(templates.FS, patterns...)
+		if a.err != nil {
+			a.err = fmt.Errorf("failed to parse partials of router %s: %w", a.Name, a.err)
+		}
+	})
+	return a.partials, a.err
+}
+
+// route is a GET route of a generated server, the data of the route
+// partials. Path is a literal path if it starts with a slash, otherwise a Go
+// expression, e.g. docs.UIPath; Handler and Middleware are Go expressions.
+type route struct {
+	Path       string
+	Handler    string
+	Middleware []string
+}
+
+// newRoute is the route function of the partials:
+//
+//	{{template "route" (route "/api/v1/me" "s.handleMe" "auth.RequireJWT(s.jwtVerifier)")}}
+func newRoute(path, handler string, middleware ...string) route {
+	return route{Path: path, Handler: handler, Middleware: middleware}
+}
+
+// Expr returns the Go expression of the path between before and after,
+// e.g. "GET /health" or "GET "+docs.UIPath+"/".
+func (r route) Expr(before, after string) string {
+	if strings.HasPrefix(r.Path, "/") {
+		return strconv.Quote(before + r.Path + after)
+	}
+	expr := r.Path
+	if before != "" {
+		expr = strconv.Quote(before) + "+" + expr
+	}
+	if after != "" {
+		expr += "+" + strconv.Quote(after)
+	}
+	return expr
+}
+
+// Wrap returns handler wrapped by the middleware, the first outermost.
+func (r route) Wrap(handler string) string {
+	for i := len(r.Middleware) - 1; i >= 0; i-- {
+		handler = r.Middleware[i] + "(" + handler + ")"
+	}
+	return handler
+}
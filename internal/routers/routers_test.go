@@ -0,0 +1,164 @@
+package routers
+
+import (
+	"io/fs"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/TRiZKy/gocrete/pkg/templates"
+)
+
+// partials are the partials every router must define.
+var partials = []string{
+	"import",
+	"route",
+	"route.handler",
+	"route.prefix",
+	"server.stdimports",
+	"server.imports",
+	"server.field",
+	"server.setup",
+	"main.serve",
+	"main.shutdown",
+}
+
+func TestAdaptersDefinePartials(t *testing.T) {
+	for _, a := range Adapters {
+		for _, name := range partials {
+			if !a.Defines(name) {
+				t.Errorf("router %s does not define %q", a.Name, name)
+			}
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	data := map[string]interface{}{
+		"Router":  "fiber",
+		"OpenAPI": "manual",
+	}
+	got, err := Get("fiber").Render("server.imports", data)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if !strings.HasPrefix(got, "\n") || !strings.Contains(got, `"github.com/gofiber/fiber/v2/middleware/adaptor"`) {
+		t.Errorf("Render() = %q", got)
+	}
+
+	if _, err := Get("fiber").Render("nethttp.server", data); err == nil {
+		t.Error("Render() of a partial of other routers succeeded")
+	}
+}
+
+// routerAction matches the template actions depending on the router.
+var routerAction = regexp.MustCompile(`{{[^}]*\.(Router|NetHTTP)\b[^}]*}}`)
+
+func TestTemplatesLeaveRouterCodeToPartials(t *testing.T) {
+	err := fs.WalkDir(templates.FS, "files", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || strings.HasPrefix(path, "files/routers/") {
+			return err
+		}
+		content, err := fs.ReadFile(templates.FS, path)
+		if err != nil {
+			return err
+		}
+		if action := routerAction.Find(content); action != nil {
+			t.Errorf("%s depends on the router in %s, not in a partial", path, action)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRoute(t *testing.T) {
+	tests := []struct {
+		route         route
+		before, after string
+		want          string
+	}{
+		{newRoute("/health", "s.handleHealth"), "GET ", "", `"GET /health"`},
+		{newRoute("docs.UIPath", "s.docs"), "", "/*", `docs.UIPath+"/*"`},
+		{newRoute("docs.UIPath", "s.docs"), "GET ", "/", `"GET "+docs.UIPath+"/"`},
+		{newRoute("docs.JSONPath", "s.docs"), "", "", `docs.JSONPath`},
+	}
+	for _, tt := range tests {
+		if got := tt.route.Expr(tt.before, tt.after); got != tt.want {
+			t.Errorf("Expr(%q, %q) of %s = %s, want %s", tt.before, tt.after, tt.route.Path, got, tt.want)
+		}
+	}
+
+	r := newRoute("/admin", "s.handleMe", "requireUser", "requireAdmin")
+	if got, want := r.Wrap("h"), "requireUser(requireAdmin(h))"; got != want {
+		t.Errorf("Wrap() = %s, want %s", got, want)
+	}
+}
+
+func TestRenderRoutes(t *testing.T) {
+	data := map[string]interface{}{
+		"OpenAPI":   "manual",
+		"HasJWT":    true,
+		"HasOIDC":   true,
+		"HasAPIKey": true,
+	}
+	want := map[string][]string{
+		"chi":    {`r.With(auth.RequireJWT(s.jwtVerifier)).Get("/api/v1/me", s.handleMe)`, `r.Handle(docs.UIPath+"/*", s.docs)`},
+		"gin":    {`r.GET("/auth/login", gin.WrapF(s.oidc.Login))`, `r.GET("/admin", s.oidc.RequireUser, s.handleMe)`},
+		"fiber":  {`app.Get("/auth/login", s.oidc.Login)`, `app.Get(docs.UIPath+"*", adaptor.HTTPHandler(s.docs))`},
+		"echo":   {`e.GET("/api/v1/whoami", s.handleMe, auth.RequireAPIKey(s.apiKeys, "read"))`},
+		"stdlib": {`mux.HandleFunc("GET /health", s.handleHealth)`, `mux.Handle("GET /admin", s.oidc.RequireUser(http.HandlerFunc(s.handleMe)))`},
+	}
+	for _, a := range Adapters {
+		got, err := a.Render("server.routes", data)
+		if err != nil {
+			t.Fatalf("Render() of router %s error = %v", a.Name, err)
+		}
+		for _, line := range want[a.Name] {
+			if !strings.Contains(got, "\t"+line+"\n") {
+				t.Errorf("routes of router %s have no line %s:\n%s", a.Name, line, got)
+			}
+		}
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name     string
+		requires []string
+		want     string
+	}{
+		{"chi", []string{"github.com/go-chi/chi/v5"}, "chi"},
+		{"gin", []string{"github.com/gin-gonic/gin"}, "gin"},
+		{"echo with chi", []string{"github.com/go-chi/chi/v5", "github.com/labstack/echo/v4"}, "echo"},
+		{"no router", nil, "stdlib"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Detect(func(module string) bool {
+				for _, m := range tt.requires {
+					if m == module {
+						return true
+					}
+				}
+				return false
+			})
+			if got == nil || got.Name != tt.want {
+				t.Errorf("Detect() = %v, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGet(t *testing.T) {
+	for _, name := range Names() {
+		if Get(name) == nil {
+			t.Errorf("Get(%q) = nil", name)
+		}
+	}
+	if Get("mux") != nil {
+		t.Error("Get() of an unknown router is not nil")
+	}
+}
@@ -0,0 +1,300 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Fields of the configuration struct are described by tags:
+//
+//	env:"PORT"        environment variable; also names the YAML key (port)
+//	                  and the command-line flag (--port)
+//	default:"8080"    value used when no source sets the field
+//	secret:"true"     masked by Print
+//
+// Supported field types are string, int, float64, bool, time.Duration,
+// *url.URL (absolute URLs only) and []string (comma-separated, or a YAML
+// sequence).
+
+// configFileEnv names the YAML file to read, as does the --config flag.
+const configFileEnv = "CONFIG_FILE"
+
+// masked replaces secret values in Print.
+const masked = "********"
+
+type field struct {
+	value  reflect.Value
+	env    string
+	def    string
+	secret bool
+}
+
+func (f field) key() string {
+	return strings.ToLower(f.env)
+}
+
+func (f field) flagName() string {
+	return strings.ReplaceAll(f.key(), "_", "-")
+}
+
+func fields(cfg interface{}) []field {
+	v := reflect.ValueOf(cfg).Elem()
+	t := v.Type()
+
+	var fs []field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		env := sf.Tag.Get("env")
+		if env == "" {
+			continue
+		}
+		fs = append(fs, field{
+			value:  v.Field(i),
+			env:    env,
+			def:    sf.Tag.Get("default"),
+			secret: sf.Tag.Get("secret") == "true",
+		})
+	}
+	return fs
+}
+
+// defaultOf returns the default tag of the field name of cfg, a pointer to a
+// struct, so that code checking for a default doesn't repeat its value.
+func defaultOf(cfg interface{}, name string) string {
+	sf, _ := reflect.TypeOf(cfg).Elem().FieldByName(name)
+	return sf.Tag.Get("default")
+}
+
+// load fills cfg, a pointer to a struct, from each field's default, the YAML
+// file, the environment and the command-line flags in args, in increasing
+// order of precedence. An environment variable VAR_FILE names a file holding
+// the value of VAR, as used for Docker and Kubernetes secrets. All invalid
+// values are reported together.
+func load(cfg interface{}, args []string) error {
+	fs := fields(cfg)
+	var errs []error
+	set := func(f field, source, raw string) {
+		if err := setValue(f, raw); err != nil {
+			errs = append(errs, fmt.Errorf("%s (from %s): %w", f.env, source, err))
+		}
+	}
+
+	flagValues, configFile, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if configFile == "" {
+		configFile = os.Getenv(configFileEnv)
+	}
+
+	for _, f := range fs {
+		if f.def != "" {
+			set(f, "default", f.def)
+		}
+	}
+
+	if configFile != "" {
+		values, err := readYAML(configFile, fs)
+		if err != nil {
+			return err
+		}
+		for _, f := range fs {
+			if raw, ok := values[f.key()]; ok {
+				set(f, configFile, raw)
+			}
+		}
+	}
+
+	for _, f := range fs {
+		if path := os.Getenv(f.env + "_FILE"); path != "" {
+			content, err := os.ReadFile(path)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s_FILE: %w", f.env, err))
+				continue
+			}
+			set(f, f.env+"_FILE", strings.TrimRight(string(content), "\r\n"))
+		} else if raw, ok := os.LookupEnv(f.env); ok {
+			set(f, "environment", raw)
+		}
+	}
+
+	for _, f := range fs {
+		if raw, ok := flagValues[f.env]; ok {
+			set(f, "flag --"+f.flagName(), raw)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// parseFlags returns the raw values of the flags set in args, keyed by
+// environment variable name, and the --config flag.
+func parseFlags(fs []field, args []string) (map[string]string, string, error) {
+	values := make(map[string]string)
+	set := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+
+	configFile := set.String("config", "", "YAML configuration file (env "+configFileEnv+")")
+	for _, f := range fs {
+		set.Var(&flagValue{env: f.env, values: values, isBool: f.value.Kind() == reflect.Bool},
+			f.flagName(), "overrides env "+f.env)
+	}
+
+	if err := set.Parse(args); err != nil {
+		return nil, "", err
+	}
+	if set.NArg() > 0 {
+		return nil, "", fmt.Errorf("unexpected arguments: %s", strings.Join(set.Args(), " "))
+	}
+	return values, *configFile, nil
+}
+
+type flagValue struct {
+	env    string
+	values map[string]string
+	isBool bool
+}
+
+func (v *flagValue) String() string { return "" }
+
+func (v *flagValue) Set(raw string) error {
+	v.values[v.env] = raw
+	return nil
+}
+
+func (v *flagValue) IsBoolFlag() bool { return v.isBool }
+
+// readYAML returns the values of a flat YAML mapping, keyed like field.key.
+// Sequences are joined with commas.
+func readYAML(path string, fs []field) (map[string]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var doc map[string]yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	known := make(map[string]bool, len(fs))
+	for _, f := range fs {
+		known[f.key()] = true
+	}
+
+	values := make(map[string]string, len(doc))
+	var errs []error
+	for key, node := range doc {
+		if !known[key] {
+			errs = append(errs, fmt.Errorf("%s: unknown key %q", path, key))
+			continue
+		}
+		switch node.Kind {
+		case yaml.ScalarNode:
+			values[key] = node.Value
+		case yaml.SequenceNode:
+			var items []string
+			if err := node.Decode(&items); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %s: %w", path, key, err))
+				continue
+			}
+			values[key] = strings.Join(items, ",")
+		default:
+			errs = append(errs, fmt.Errorf("%s: %s must be a scalar or a list", path, key))
+		}
+	}
+	return values, errors.Join(errs...)
+}
+
+func setValue(f field, raw string) error {
+	v := f.value
+	switch {
+	case v.Type() == reflect.TypeOf(time.Duration(0)):
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", raw)
+		}
+		v.SetInt(int64(d))
+	case v.Type() == reflect.TypeOf((*url.URL)(nil)):
+		u, err := url.Parse(raw)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("invalid URL %q", raw)
+		}
+		v.Set(reflect.ValueOf(u))
+	case v.Kind() == reflect.String:
+		v.SetString(raw)
+	case v.Kind() == reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		v.SetInt(int64(n))
+	case v.Kind() == reflect.Float64:
+		n, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", raw)
+		}
+		v.SetFloat(n)
+	case v.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", raw)
+		}
+		v.SetBool(b)
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String:
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported field type %s", v.Type())
+	}
+	return nil
+}
+
+// printYAML writes cfg as YAML, in the format read from the config file, with
+// the values of secret fields masked.
+func printYAML(w io.Writer, cfg interface{}) error {
+	doc := &yaml.Node{Kind: yaml.MappingNode}
+	for _, f := range fields(cfg) {
+		value := &yaml.Node{Kind: yaml.ScalarNode}
+		switch {
+		case f.secret:
+			if !f.value.IsZero() {
+				value.Value = masked
+			}
+		case f.value.Kind() == reflect.Slice:
+			value.Kind = yaml.SequenceNode
+			value.Style = yaml.FlowStyle
+			for i := 0; i < f.value.Len(); i++ {
+				value.Content = append(value.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: f.value.Index(i).String()})
+			}
+		case f.value.Kind() == reflect.Pointer:
+			if !f.value.IsNil() {
+				value.Value = fmt.Sprint(f.value.Interface())
+			}
+		default:
+			value.Value = fmt.Sprint(f.value.Interface())
+		}
+		doc.Content = append(doc.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: f.key()}, value)
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	return enc.Close()
+}
@@ -0,0 +1,172 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testConfig struct {
+	Port     int           `env:"TEST_PORT" default:"8080"`
+	Debug    bool          `env:"TEST_DEBUG"`
+	Timeout  time.Duration `env:"TEST_TIMEOUT" default:"30s"`
+	Hosts    []string      `env:"TEST_HOSTS" default:"a,b"`
+	Endpoint *url.URL      `env:"TEST_ENDPOINT" default:"http://localhost:4318"`
+	Callback *url.URL      `env:"TEST_CALLBACK"`
+	Password string        `env:"TEST_PASSWORD" secret:"true"`
+	Name     string        `env:"TEST_NAME" default:"api"`
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	var cfg testConfig
+	if err := load(&cfg, nil); err != nil {
+		t.Fatal(err)
+	}
+	want := testConfig{
+		Port:     8080,
+		Timeout:  30 * time.Second,
+		Hosts:    []string{"a", "b"},
+		Endpoint: &url.URL{Scheme: "http", Host: "localhost:4318"},
+		Name:     "api",
+	}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("load() = %+v, want %+v", cfg, want)
+	}
+}
+
+func TestDefaultOf(t *testing.T) {
+	cfg := &testConfig{}
+	if got := defaultOf(cfg, "Name"); got != "api" {
+		t.Errorf("defaultOf(Name) = %q, want api", got)
+	}
+	if got := defaultOf(cfg, "Password"); got != "" {
+		t.Errorf("defaultOf(Password) = %q, want none", got)
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	file := writeFile(t, "config.yaml", "test_port: 8081\ntest_name: from-file\ntest_hosts: [x, y]\ntest_debug: true\n")
+	t.Setenv("CONFIG_FILE", file)
+	t.Setenv("TEST_PORT", "8082")
+	t.Setenv("TEST_PASSWORD_FILE", writeFile(t, "password", "s3cret\n"))
+
+	var cfg testConfig
+	if err := load(&cfg, []string{"--test-port", "8083", "--test-timeout=1m"}); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Port != 8083 {
+		t.Errorf("Port = %d, want the flag value 8083", cfg.Port)
+	}
+	if cfg.Name != "from-file" || !cfg.Debug || !reflect.DeepEqual(cfg.Hosts, []string{"x", "y"}) {
+		t.Errorf("file values not applied: %+v", cfg)
+	}
+	if cfg.Timeout != time.Minute {
+		t.Errorf("Timeout = %v, want 1m", cfg.Timeout)
+	}
+	if cfg.Password != "s3cret" {
+		t.Errorf("Password = %q, want the content of TEST_PASSWORD_FILE", cfg.Password)
+	}
+}
+
+func TestLoadEmptyEnvironmentOverridesDefault(t *testing.T) {
+	t.Setenv("TEST_NAME", "")
+	t.Setenv("TEST_HOSTS", "")
+
+	var cfg testConfig
+	if err := load(&cfg, nil); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Name != "" || cfg.Hosts != nil {
+		t.Errorf("Name = %q, Hosts = %v, want both emptied by the environment", cfg.Name, cfg.Hosts)
+	}
+}
+
+func TestLoadURLs(t *testing.T) {
+	t.Setenv("TEST_CALLBACK", "https://example.com/auth/callback?x=1")
+
+	var cfg testConfig
+	if err := load(&cfg, nil); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Callback == nil || cfg.Callback.Host != "example.com" || cfg.Callback.Path != "/auth/callback" {
+		t.Errorf("Callback = %v, want the parsed URL", cfg.Callback)
+	}
+
+	for _, raw := range []string{"", "localhost", "/callback", "http://%zz"} {
+		t.Setenv("TEST_CALLBACK", raw)
+		if err := load(&testConfig{}, nil); err == nil || !strings.Contains(err.Error(), "TEST_CALLBACK") {
+			t.Errorf("load() with TEST_CALLBACK=%q error = %v, want invalid URL", raw, err)
+		}
+	}
+}
+
+func TestLoadAggregatesErrors(t *testing.T) {
+	t.Setenv("TEST_PORT", "eighty")
+	t.Setenv("TEST_DEBUG", "maybe")
+	t.Setenv("TEST_ENDPOINT", "localhost")
+
+	var cfg testConfig
+	err := load(&cfg, []string{"--test-timeout", "soon"})
+	if err == nil {
+		t.Fatal("load() succeeded with invalid values")
+	}
+	for _, want := range []string{"TEST_PORT", "TEST_DEBUG", "TEST_ENDPOINT", "TEST_TIMEOUT (from flag --test-timeout)"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %s", err, want)
+		}
+	}
+}
+
+func TestLoadRejectsUnknownKeys(t *testing.T) {
+	t.Setenv("CONFIG_FILE", writeFile(t, "config.yaml", "test_prot: 8081\n"))
+
+	var cfg testConfig
+	if err := load(&cfg, nil); err == nil || !strings.Contains(err.Error(), "test_prot") {
+		t.Errorf("load() error = %v, want unknown key test_prot", err)
+	}
+}
+
+func TestLoadHelp(t *testing.T) {
+	var cfg testConfig
+	if err := load(&cfg, []string{"-h"}); !errors.Is(err, flag.ErrHelp) {
+		t.Errorf("load(-h) error = %v, want flag.ErrHelp", err)
+	}
+}
+
+func TestPrintMasksSecrets(t *testing.T) {
+	t.Setenv("TEST_PASSWORD", "s3cret")
+
+	var cfg testConfig
+	if err := load(&cfg, nil); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := printYAML(&buf, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if strings.Contains(out, "s3cret") || !strings.Contains(out, masked) {
+		t.Errorf("secret not masked:\n%s", out)
+	}
+	for _, want := range []string{"test_port: 8080", "test_timeout: 30s", "test_hosts: [a, b]", "test_endpoint: http://localhost:4318"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
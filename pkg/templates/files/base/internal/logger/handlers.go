@@ -0,0 +1,261 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// redacted replaces the values of sensitive attributes.
+const redacted = "[REDACTED]"
+
+// redactor holds the lowercased keys of sensitive attributes.
+type redactor map[string]bool
+
+func newRedactor(keys []string) redactor {
+	r := make(redactor, len(keys))
+	for _, key := range keys {
+		r[strings.ToLower(strings.TrimSpace(key))] = true
+	}
+	return r
+}
+
+func (r redactor) sensitive(key string) bool {
+	return r[strings.ToLower(key)]
+}
+
+// attr replaces the value of a if its key is sensitive, and otherwise the
+// sensitive attributes and map entries nested in its value.
+func (r redactor) attr(a slog.Attr) slog.Attr {
+	if r.sensitive(a.Key) {
+		return slog.String(a.Key, redacted)
+	}
+
+	a.Value = a.Value.Resolve()
+	switch a.Value.Kind() {
+	case slog.KindGroup:
+		attrs := a.Value.Group()
+		nested := make([]slog.Attr, len(attrs))
+		for i, attr := range attrs {
+			nested[i] = r.attr(attr)
+		}
+		a.Value = slog.GroupValue(nested...)
+	case slog.KindAny:
+		if m, ok := r.mapValue(reflect.ValueOf(a.Value.Any())); ok {
+			a.Value = slog.AnyValue(m)
+		}
+	}
+	return a
+}
+
+// mapValue copies maps with string keys, such as http.Header, replacing the
+// values of sensitive keys at any depth. It reports false for other values.
+func (r redactor) mapValue(v reflect.Value) (map[string]interface{}, bool) {
+	if v.Kind() != reflect.Map || v.Type().Key().Kind() != reflect.String {
+		return nil, false
+	}
+
+	m := make(map[string]interface{}, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		key, value := iter.Key().String(), iter.Value()
+		if value.Kind() == reflect.Interface {
+			value = value.Elem()
+		}
+		switch nested, ok := r.mapValue(value); {
+		case r.sensitive(key):
+			m[key] = redacted
+		case ok:
+			m[key] = nested
+		case value.IsValid():
+			m[key] = value.Interface()
+		default:
+			m[key] = nil
+		}
+	}
+	return m, true
+}
+
+// redactHandler replaces the values of sensitive attributes before the
+// wrapped handler formats them: at any depth of groups and maps, and all
+// attributes of a group opened with a sensitive name.
+type redactHandler struct {
+	slog.Handler
+	redactor redactor
+	all      bool
+}
+
+func newRedactHandler(h slog.Handler, keys []string) redactHandler {
+	return redactHandler{Handler: h, redactor: newRedactor(keys)}
+}
+
+func (h redactHandler) Handle(ctx context.Context, r slog.Record) error {
+	redactedRecord := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		redactedRecord.AddAttrs(h.attr(a))
+		return true
+	})
+	return h.Handler.Handle(ctx, redactedRecord)
+}
+
+func (h redactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redactedAttrs := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		redactedAttrs[i] = h.attr(a)
+	}
+	return redactHandler{Handler: h.Handler.WithAttrs(redactedAttrs), redactor: h.redactor, all: h.all}
+}
+
+func (h redactHandler) WithGroup(name string) slog.Handler {
+	return redactHandler{Handler: h.Handler.WithGroup(name), redactor: h.redactor, all: h.all || h.redactor.sensitive(name)}
+}
+
+func (h redactHandler) attr(a slog.Attr) slog.Attr {
+	if h.all {
+		return slog.String(a.Key, redacted)
+	}
+	return h.redactor.attr(a)
+}
+
+// samplingHandler lets through the first n debug records with the same
+// message each second, then every nth. Records at info and above are never
+// dropped.
+type samplingHandler struct {
+	slog.Handler
+	n       int
+	sampler *sampler
+}
+
+// sampler counts the debug records of the current second by message. The
+// counts are dropped when the second changes, so messages logged once do
+// not accumulate.
+type sampler struct {
+	mu     sync.Mutex
+	second int64
+	counts map[string]int
+}
+
+func newSamplingHandler(h slog.Handler, n int) samplingHandler {
+	return samplingHandler{Handler: h, n: n, sampler: &sampler{counts: make(map[string]int)}}
+}
+
+func (h samplingHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level < slog.LevelInfo && !h.sample(r) {
+		return nil
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h samplingHandler) sample(r slog.Record) bool {
+	s := h.sampler
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if second := r.Time.Unix(); second != s.second {
+		s.second = second
+		s.counts = make(map[string]int)
+	}
+	s.counts[r.Message]++
+	count := s.counts[r.Message]
+	return count <= h.n || (count-h.n)%h.n == 0
+}
+
+func (h samplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return samplingHandler{Handler: h.Handler.WithAttrs(attrs), n: h.n, sampler: h.sampler}
+}
+
+func (h samplingHandler) WithGroup(name string) slog.Handler {
+	return samplingHandler{Handler: h.Handler.WithGroup(name), n: h.n, sampler: h.sampler}
+}
+
+// prettyHandler writes colored, human-readable lines for local development:
+//
+//	15:04:05.000 INF request method=GET path=/health status=200
+//
+// Attributes are formatted by a slog.TextHandler writing into a buffer shared
+// by all handlers derived from the same root.
+type prettyHandler struct {
+	attrs slog.Handler
+	buf   *bytes.Buffer
+	mu    *sync.Mutex
+	out   io.Writer
+}
+
+func newPrettyHandler(out io.Writer, opts *slog.HandlerOptions) *prettyHandler {
+	h := &prettyHandler{buf: new(bytes.Buffer), mu: new(sync.Mutex), out: out}
+
+	textOpts := *opts
+	textOpts.ReplaceAttr = func(groups []string, a slog.Attr) slog.Attr {
+		// Time, level and message lead the line instead. Attributes that
+		// merely share their keys are kept.
+		if len(groups) == 0 && isBuiltin(a) {
+			return slog.Attr{}
+		}
+		if opts.ReplaceAttr != nil {
+			return opts.ReplaceAttr(groups, a)
+		}
+		return a
+	}
+	h.attrs = slog.NewTextHandler(h.buf, &textOpts)
+	return h
+}
+
+func (h *prettyHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.attrs.Enabled(ctx, level)
+}
+
+func (h *prettyHandler) Handle(ctx context.Context, r slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.buf.Reset()
+	if err := h.attrs.Handle(ctx, r); err != nil {
+		return err
+	}
+
+	line := fmt.Sprintf("%s %s %s", r.Time.Format("15:04:05.000"), prettyLevel(r.Level), r.Message)
+	if attrs := bytes.TrimSpace(h.buf.Bytes()); len(attrs) > 0 {
+		line += " \x1b[2m" + string(attrs) + "\x1b[0m"
+	}
+	_, err := io.WriteString(h.out, line+"\n")
+	return err
+}
+
+func (h *prettyHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &prettyHandler{attrs: h.attrs.WithAttrs(attrs), buf: h.buf, mu: h.mu, out: h.out}
+}
+
+func (h *prettyHandler) WithGroup(name string) slog.Handler {
+	return &prettyHandler{attrs: h.attrs.WithGroup(name), buf: h.buf, mu: h.mu, out: h.out}
+}
+
+func isBuiltin(a slog.Attr) bool {
+	switch a.Key {
+	case slog.TimeKey:
+		return a.Value.Kind() == slog.KindTime
+	case slog.LevelKey:
+		_, ok := a.Value.Any().(slog.Level)
+		return ok
+	case slog.MessageKey:
+		return true
+	}
+	return false
+}
+
+func prettyLevel(level slog.Level) string {
+	switch {
+	case level >= slog.LevelError:
+		return "\x1b[31mERR\x1b[0m"
+	case level >= slog.LevelWarn:
+		return "\x1b[33mWRN\x1b[0m"
+	case level >= slog.LevelInfo:
+		return "\x1b[32mINF\x1b[0m"
+	default:
+		return "\x1b[90mDBG\x1b[0m"
+	}
+}
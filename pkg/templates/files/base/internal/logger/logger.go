@@ -1,34 +1,299 @@
 package logger
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
 	"log/slog"
+	"net/http"
 	"os"
+	"strings"
+	"sync"
 )
 
+// RequestIDHeader carries the request ID on incoming requests and responses.
+const RequestIDHeader = "X-Request-ID"
+
+// DefaultRedactKeys are the attribute keys redacted when Options.RedactKeys
+// is nil.
+var DefaultRedactKeys = []string{"password", "secret", "token", "authorization", "cookie", "api_key", "email"}
+
 type Logger struct {
 	*slog.Logger
+	level *slog.LevelVar
 }
 
+// Options configures a logger created with NewWithOptions.
+type Options struct {
+	// Level is the initial minimum level: debug, info, warn or error.
+	Level string
+	// Format is json, text or pretty (colored, for terminals).
+	Format string
+	// AddSource adds the file and line of the logging call.
+	AddSource bool
+	// RedactKeys lists attribute keys, matched case-insensitively at any
+	// depth of groups and maps, whose values are replaced. Nil means
+	// DefaultRedactKeys.
+	RedactKeys []string
+	// SampleDebug limits debug records: each message is logged SampleDebug
+	// times per second, then once every SampleDebug records. Zero disables
+	// sampling.
+	SampleDebug int
+	// Output defaults to os.Stdout.
+	Output io.Writer
+}
+
+// New returns a JSON logger writing records at level and above to stdout.
 func New(level string) *Logger {
-	var logLevel slog.Level
+	return NewWithOptions(Options{Level: level})
+}
+
+// NewWithOptions returns a logger whose level can be changed at runtime with
+// SetLevel or LevelHandler.
+func NewWithOptions(opts Options) *Logger {
+	out := opts.Output
+	if out == nil {
+		out = os.Stdout
+	}
+	levelVar := new(slog.LevelVar)
+	levelVar.Set(parseLevel(opts.Level))
+
+	redact := opts.RedactKeys
+	if redact == nil {
+		redact = DefaultRedactKeys
+	}
+	handlerOpts := &slog.HandlerOptions{
+		Level:     levelVar,
+		AddSource: opts.AddSource,
+	}
+
+	var handler slog.Handler
+	switch opts.Format {
+	case "text":
+		handler = slog.NewTextHandler(out, handlerOpts)
+	case "pretty":
+		handler = newPrettyHandler(out, handlerOpts)
+	default:
+		handler = slog.NewJSONHandler(out, handlerOpts)
+	}
+	handler = newRedactHandler(handler, redact)
+	if opts.SampleDebug > 0 {
+		handler = newSamplingHandler(handler, opts.SampleDebug)
+	}
+
+	return &Logger{
+		Logger: slog.New(contextHandler{handler}),
+		level:  levelVar,
+	}
+}
+
+// WithHandler returns a copy of l logging through h, typically a wrapper of
+// l.Handler(). The copy shares l's adjustable level.
+func (l *Logger) WithHandler(h slog.Handler) *Logger {
+	return &Logger{Logger: slog.New(h), level: l.level}
+}
+
+// Level returns the current minimum level.
+func (l *Logger) Level() string {
+	if l.level == nil {
+		return ""
+	}
+	return strings.ToLower(l.level.Level().String())
+}
+
+// SetLevel changes the minimum level of l and of every logger derived from it.
+func (l *Logger) SetLevel(level string) error {
+	if l.level == nil {
+		return fmt.Errorf("logger level is not adjustable")
+	}
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return fmt.Errorf("invalid log level: %s", level)
+	}
+	l.level.Set(lvl)
+	return nil
+}
+
+// LevelHandler serves the current level on GET and changes it on PUT, with
+// a body like {"level":"debug"}. Serve it on the admin port only.
+func (l *Logger) LevelHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			var body struct {
+				Level string `json:"level"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				http.Error(w, "invalid request body", http.StatusBadRequest)
+				return
+			}
+			if err := l.SetLevel(body.Level); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			l.Warn("log level changed", "level", l.Level())
+		default:
+			w.Header().Set("Allow", "GET, PUT")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"level": l.Level()})
+	})
+}
+
+func parseLevel(level string) slog.Level {
 	switch level {
 	case "debug":
-		logLevel = slog.LevelDebug
-	case "info":
-		logLevel = slog.LevelInfo
+		return slog.LevelDebug
 	case "warn":
-		logLevel = slog.LevelWarn
+		return slog.LevelWarn
 	case "error":
-		logLevel = slog.LevelError
+		return slog.LevelError
 	default:
-		logLevel = slog.LevelInfo
+		return slog.LevelInfo
+	}
+}
+
+type loggerKey struct{}
+
+type attrsKey struct{}
+
+type requestIDKey struct{}
+
+// requestAttrs holds the attributes added with AddAttrs. It is shared by every
+// context derived from the one passed to WithContext, so attributes added by
+// inner middleware, e.g. the user ID after authentication, also appear on
+// records logged by outer middleware.
+type requestAttrs struct {
+	mu    sync.Mutex
+	attrs []slog.Attr
+}
+
+// WithContext returns a copy of ctx carrying l. Records logged with the
+// returned context, or a context derived from it, include the attributes
+// added with AddAttrs.
+func WithContext(ctx context.Context, l *Logger) context.Context {
+	ctx = context.WithValue(ctx, loggerKey{}, l)
+	if _, ok := ctx.Value(attrsKey{}).(*requestAttrs); !ok {
+		ctx = context.WithValue(ctx, attrsKey{}, &requestAttrs{})
+	}
+	return ctx
+}
+
+// FromContext returns the logger stored in ctx by WithContext, or the default
+// slog logger. Records logged through it without a context, e.g. with Info
+// instead of InfoContext, are logged with ctx.
+func FromContext(ctx context.Context) *Logger {
+	l, ok := ctx.Value(loggerKey{}).(*Logger)
+	if !ok {
+		l = &Logger{Logger: slog.Default()}
 	}
+	return l.WithHandler(boundHandler{Handler: l.Handler(), ctx: ctx})
+}
+
+// AddAttrs adds attributes, given as key-value pairs or slog.Attr values, to
+// every record logged with ctx for the rest of the request. It does nothing if
+// ctx does not derive from a context returned by WithContext.
+func AddAttrs(ctx context.Context, args ...any) {
+	ra, ok := ctx.Value(attrsKey{}).(*requestAttrs)
+	if !ok {
+		return
+	}
+
+	var r slog.Record
+	r.Add(args...)
 
-	handler := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
-		Level: logLevel,
+	ra.mu.Lock()
+	defer ra.mu.Unlock()
+	r.Attrs(func(a slog.Attr) bool {
+		ra.attrs = append(ra.attrs, a)
+		return true
 	})
+}
 
-	return &Logger{
-		Logger: slog.New(handler),
+// NewRequestID returns a random request ID.
+func NewRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// EnsureRequestID returns id, typically the RequestIDHeader of an incoming
+// request, if it is safe to log and echo back, or a new request ID otherwise.
+func EnsureRequestID(id string) string {
+	if id == "" || len(id) > 128 {
+		return NewRequestID()
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.':
+		default:
+			return NewRequestID()
+		}
 	}
+	return id
+}
+
+// WithRequestID returns a copy of ctx carrying id and adds it as the
+// request_id attribute of the request's records.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	AddAttrs(ctx, "request_id", id)
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID stored in ctx, if any.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// contextHandler adds the attributes stored in the record's context.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if ra, ok := ctx.Value(attrsKey{}).(*requestAttrs); ok {
+		ra.mu.Lock()
+		r.AddAttrs(ra.attrs...)
+		ra.mu.Unlock()
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// boundHandler logs records that were logged without a context with the
+// context the logger was retrieved from.
+type boundHandler struct {
+	slog.Handler
+	ctx context.Context
+}
+
+func (h boundHandler) Handle(ctx context.Context, r slog.Record) error {
+	if ctx == context.Background() {
+		ctx = h.ctx
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h boundHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return boundHandler{Handler: h.Handler.WithAttrs(attrs), ctx: h.ctx}
+}
+
+func (h boundHandler) WithGroup(name string) slog.Handler {
+	return boundHandler{Handler: h.Handler.WithGroup(name), ctx: h.ctx}
 }
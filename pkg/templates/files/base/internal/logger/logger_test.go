@@ -0,0 +1,217 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestLogger(buf *bytes.Buffer) *Logger {
+	return &Logger{Logger: slog.New(contextHandler{slog.NewJSONHandler(buf, nil)})}
+}
+
+func decode(t *testing.T, buf *bytes.Buffer) map[string]interface{} {
+	t.Helper()
+	var record map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("invalid log record %q: %v", buf.String(), err)
+	}
+	buf.Reset()
+	return record
+}
+
+func TestContextAttributes(t *testing.T) {
+	var buf bytes.Buffer
+	log := newTestLogger(&buf)
+
+	ctx := WithRequestID(WithContext(context.Background(), log), "req-1")
+	// Attributes added on a derived context, as authentication middleware
+	// does, are visible on records logged with the outer context.
+	inner, cancel := context.WithCancel(ctx)
+	defer cancel()
+	AddAttrs(inner, "user_id", "u-42")
+
+	log.InfoContext(ctx, "request")
+	record := decode(t, &buf)
+	if record["request_id"] != "req-1" || record["user_id"] != "u-42" {
+		t.Errorf("InfoContext record = %v, want request_id and user_id", record)
+	}
+
+	FromContext(inner).With("component", "handler").Info("handled")
+	record = decode(t, &buf)
+	if record["request_id"] != "req-1" || record["user_id"] != "u-42" || record["component"] != "handler" {
+		t.Errorf("FromContext record = %v, want request_id, user_id and component", record)
+	}
+
+	log.Info("unrelated")
+	if record := decode(t, &buf); record["request_id"] != nil {
+		t.Errorf("record logged without context has request_id: %v", record)
+	}
+
+	if got := RequestID(inner); got != "req-1" {
+		t.Errorf("RequestID() = %q, want req-1", got)
+	}
+}
+
+func TestFromContextWithoutLogger(t *testing.T) {
+	if FromContext(context.Background()) == nil {
+		t.Fatal("FromContext() = nil, want the default logger")
+	}
+	// Must not panic without a logger in the context
+	AddAttrs(context.Background(), "user_id", "u-42")
+}
+
+func TestEnsureRequestID(t *testing.T) {
+	tests := []struct {
+		name string
+		id   string
+		keep bool
+	}{
+		{name: "uuid", id: "3f2b8c1e-9d4a-4e5f-8a7b-1c2d3e4f5a6b", keep: true},
+		{name: "empty", id: "", keep: false},
+		{name: "newline", id: "abc\ninjected", keep: false},
+		{name: "too long", id: string(bytes.Repeat([]byte("a"), 129)), keep: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := EnsureRequestID(tt.id)
+			if (got == tt.id) != tt.keep {
+				t.Errorf("EnsureRequestID(%q) = %q, keep = %v", tt.id, got, tt.keep)
+			}
+			if got == "" {
+				t.Error("EnsureRequestID() returned an empty ID")
+			}
+		})
+	}
+}
+
+func TestRedaction(t *testing.T) {
+	var buf bytes.Buffer
+	log := NewWithOptions(Options{Output: &buf})
+
+	log.Info("login",
+		"Password", "hunter2",
+		slog.Group("user", "id", "u-42", "email", "a@example.com"),
+	)
+	record := decode(t, &buf)
+	if record["Password"] != redacted {
+		t.Errorf("Password = %v, want redacted", record["Password"])
+	}
+	user, _ := record["user"].(map[string]interface{})
+	if user["email"] != redacted || user["id"] != "u-42" {
+		t.Errorf("user = %v, want only email redacted", user)
+	}
+}
+
+func TestRedactionNested(t *testing.T) {
+	var buf bytes.Buffer
+	log := NewWithOptions(Options{Output: &buf})
+
+	log.With(slog.Group("session", "token", "t-1")).
+		WithGroup("request").
+		Info("call",
+			slog.Group("body", slog.Group("secret", "value", "s-1"), "name", "n-1"),
+			"headers", http.Header{"Authorization": {"Bearer b-1"}, "Accept": {"*/*"}},
+			"params", map[string]interface{}{"nested": map[string]string{"api_key": "k-1"}},
+		)
+	line := buf.String()
+	for _, leaked := range []string{"t-1", "s-1", "b-1", "k-1"} {
+		if strings.Contains(line, leaked) {
+			t.Errorf("record leaks %q: %s", leaked, line)
+		}
+	}
+	for _, kept := range []string{"n-1", "*/*"} {
+		if !strings.Contains(line, kept) {
+			t.Errorf("record lost %q: %s", kept, line)
+		}
+	}
+
+	buf.Reset()
+	log.WithGroup("secret").Info("call", "user", "u-1")
+	if strings.Contains(buf.String(), "u-1") {
+		t.Errorf("record leaks attributes of a sensitive group: %s", buf.String())
+	}
+}
+
+func TestSampleDebug(t *testing.T) {
+	var buf bytes.Buffer
+	log := NewWithOptions(Options{Level: "debug", Output: &buf, SampleDebug: 2})
+
+	for i := 0; i < 6; i++ {
+		log.Debug("cache hit")
+	}
+	log.Info("not sampled")
+
+	// The first 2 each second, then every 2nd of the rest; a second boundary
+	// during the loop can only let more through.
+	lines := bytes.Count(buf.Bytes(), []byte("cache hit"))
+	if lines < 4 || lines == 6 {
+		t.Errorf("logged %d of 6 debug records, want 4 or 5", lines)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("not sampled")) {
+		t.Error("info record was sampled")
+	}
+}
+
+func TestSampleDebugResetsEachSecond(t *testing.T) {
+	h := newSamplingHandler(slog.NewJSONHandler(io.Discard, nil), 1)
+	now := time.Now()
+
+	for _, msg := range []string{"a", "b", "c"} {
+		h.Handle(context.Background(), slog.NewRecord(now, slog.LevelDebug, msg, 0))
+	}
+	h.Handle(context.Background(), slog.NewRecord(now.Add(time.Second), slog.LevelDebug, "d", 0))
+
+	if n := len(h.sampler.counts); n != 1 {
+		t.Errorf("sampler counts %d messages, want only the 1 of the current second", n)
+	}
+}
+
+func TestLevelHandler(t *testing.T) {
+	var buf bytes.Buffer
+	log := NewWithOptions(Options{Level: "info", Output: &buf})
+	derived := FromContext(WithContext(context.Background(), log))
+	handler := log.LevelHandler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/log/level", strings.NewReader(`{"level":"debug"}`)))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"debug"`) {
+		t.Fatalf("PUT level = %d %s", rec.Code, rec.Body.String())
+	}
+
+	buf.Reset()
+	derived.Debug("now visible")
+	if !bytes.Contains(buf.Bytes(), []byte("now visible")) {
+		t.Error("debug record dropped after raising the level")
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/log/level", strings.NewReader(`{"level":"loud"}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("PUT invalid level status = %d, want 400", rec.Code)
+	}
+}
+
+func TestPrettyFormat(t *testing.T) {
+	var buf bytes.Buffer
+	log := NewWithOptions(Options{Format: "pretty", Output: &buf})
+
+	log.With("component", "test").Info("started", "token", "abc", "level", "debug")
+	line := buf.String()
+	for _, want := range []string{"INF", "started", "component=test", "token=" + redacted, "level=debug"} {
+		if !strings.Contains(line, want) {
+			t.Errorf("line %q missing %q", line, want)
+		}
+	}
+	if strings.Contains(line, "msg=") || strings.Contains(line, "level=INFO") {
+		t.Errorf("line %q repeats the message or level as an attribute", line)
+	}
+}
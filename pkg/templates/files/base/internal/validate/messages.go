@@ -0,0 +1,87 @@
+package validate
+
+import "strings"
+
+// DefaultLanguage is used when the client accepts none of the languages in
+// Messages.
+const DefaultLanguage = "en"
+
+// Messages holds the validation messages by language and rule. {param} is
+// replaced by the rule's parameter, e.g. 3 in min=3. Add a language by adding
+// its entries; missing rules fall back to DefaultLanguage.
+var Messages = map[string]map[string]string{
+	"en": {
+		"required":      "is required",
+		"min":           "must be at least {param}",
+		"max":           "must be at most {param}",
+		"min_len":       "must be at least {param} characters long",
+		"max_len":       "must be at most {param} characters long",
+		"len":           "must be exactly {param} characters long",
+		"min_items":     "must contain at least {param} items",
+		"max_items":     "must contain at most {param} items",
+		"len_items":     "must contain exactly {param} items",
+		"email":         "must be a valid email address",
+		"url":           "must be a valid URL",
+		"uuid":          "must be a valid UUID",
+		"oneof":         "must be one of: {param}",
+		"type":          "must be of type {param}",
+		"unknown_field": "is not a known field",
+	},
+	"es": {
+		"required":      "es obligatorio",
+		"min":           "debe ser como mínimo {param}",
+		"max":           "debe ser como máximo {param}",
+		"min_len":       "debe tener al menos {param} caracteres",
+		"max_len":       "debe tener como máximo {param} caracteres",
+		"len":           "debe tener exactamente {param} caracteres",
+		"min_items":     "debe contener al menos {param} elementos",
+		"max_items":     "debe contener como máximo {param} elementos",
+		"len_items":     "debe contener exactamente {param} elementos",
+		"email":         "debe ser un correo electrónico válido",
+		"url":           "debe ser una URL válida",
+		"uuid":          "debe ser un UUID válido",
+		"oneof":         "debe ser uno de: {param}",
+		"type":          "debe ser de tipo {param}",
+		"unknown_field": "no es un campo conocido",
+	},
+	"de": {
+		"required":      "ist erforderlich",
+		"min":           "muss mindestens {param} sein",
+		"max":           "darf höchstens {param} sein",
+		"min_len":       "muss mindestens {param} Zeichen lang sein",
+		"max_len":       "darf höchstens {param} Zeichen lang sein",
+		"len":           "muss genau {param} Zeichen lang sein",
+		"min_items":     "muss mindestens {param} Einträge enthalten",
+		"max_items":     "darf höchstens {param} Einträge enthalten",
+		"len_items":     "muss genau {param} Einträge enthalten",
+		"email":         "muss eine gültige E-Mail-Adresse sein",
+		"url":           "muss eine gültige URL sein",
+		"uuid":          "muss eine gültige UUID sein",
+		"oneof":         "muss einer der folgenden Werte sein: {param}",
+		"type":          "muss vom Typ {param} sein",
+		"unknown_field": "ist kein bekanntes Feld",
+	},
+}
+
+// Language returns the first language of an Accept-Language header that has
+// messages, or DefaultLanguage. Quality values are ignored; clients list
+// languages in order of preference.
+func Language(acceptLanguage string) string {
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, _, _ := strings.Cut(strings.TrimSpace(part), ";")
+		base, _, _ := strings.Cut(strings.ToLower(tag), "-")
+		if _, ok := Messages[base]; ok {
+			return base
+		}
+	}
+	return DefaultLanguage
+}
+
+// message returns the message for rule in lang.
+func message(lang, rule, param string) string {
+	msg, ok := Messages[lang][rule]
+	if !ok {
+		msg = Messages[DefaultLanguage][rule]
+	}
+	return strings.ReplaceAll(msg, "{param}", param)
+}
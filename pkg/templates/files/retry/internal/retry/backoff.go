@@ -0,0 +1,23 @@
+// Package retry computes the delays between attempts of failed work, such as
+// jobs and outbox events.
+package retry
+
+import (
+	"time"
+)
+
+// Backoff returns the delay before retrying after the given attempt
+// (starting at 1): base doubled for every previous attempt, capped at max.
+func Backoff(attempt int, base, max time.Duration) time.Duration {
+	delay := base
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= max {
+			return max
+		}
+	}
+	if delay > max {
+		return max
+	}
+	return delay
+}
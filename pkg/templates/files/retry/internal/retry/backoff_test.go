@@ -0,0 +1,28 @@
+package retry
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		name    string
+		attempt int
+		want    time.Duration
+	}{
+		{name: "first attempt", attempt: 1, want: time.Second},
+		{name: "second attempt", attempt: 2, want: 2 * time.Second},
+		{name: "fifth attempt", attempt: 5, want: 16 * time.Second},
+		{name: "capped", attempt: 20, want: time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Backoff(tt.attempt, time.Second, time.Minute)
+			if got != tt.want {
+				t.Errorf("Backoff(%d) = %v, want %v", tt.attempt, got, tt.want)
+			}
+		})
+	}
+}
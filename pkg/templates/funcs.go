@@ -0,0 +1,268 @@
+package templates
+
+import (
+	"fmt"
+	"go/token"
+	"reflect"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Funcs returns the functions available to every template:
+//
+//	camel, pascal, snake, kebab  convert a name's case: userID, UserID, user_id, user-id
+//	plural, singular             convert an English noun: users, user
+//	goIdent                      convert a name to an unexported Go identifier that is not a keyword
+//	toYAML                       encode a value as YAML, without the final newline
+//	indent                       indent the non-empty lines of a string by n spaces
+//	join                         join a list with a separator
+//	default                      return a default for an empty value
+//	required                     fail the rendering with a message for an empty value
+//
+// Functions taking a value and a parameter take the value last, so they
+// can be used in pipelines: {{.Name | default "app" | kebab}}.
+func Funcs() template.FuncMap {
+	return template.FuncMap{
+		"camel":    Camel,
+		"pascal":   Pascal,
+		"snake":    Snake,
+		"kebab":    Kebab,
+		"plural":   Plural,
+		"singular": Singular,
+		"goIdent":  GoIdent,
+		"toYAML":   toYAML,
+		"indent":   indent,
+		"join":     join,
+		"default":  defaultValue,
+		"required": required,
+	}
+}
+
+// initialisms are written in upper case in Go names, e.g. UserID.
+var initialisms = map[string]bool{
+	"API": true, "HTML": true, "HTTP": true, "HTTPS": true, "ID": true,
+	"IP": true, "JSON": true, "JWT": true, "SQL": true, "URI": true,
+	"URL": true, "UUID": true, "XML": true,
+}
+
+// Pascal converts a name, such as list_users, listUsers or user-id, to an
+// exported Go name: ListUsers, UserID.
+func Pascal(s string) string {
+	var b strings.Builder
+	for _, word := range words(s) {
+		if upper := strings.ToUpper(word); initialisms[upper] {
+			b.WriteString(upper)
+			continue
+		}
+		runes := []rune(word)
+		b.WriteRune(unicode.ToUpper(runes[0]))
+		b.WriteString(string(runes[1:]))
+	}
+	name := b.String()
+	if name == "" || !unicode.IsLetter([]rune(name)[0]) {
+		name = "X" + name
+	}
+	return name
+}
+
+// Camel converts a name to an unexported Go name: listUsers, userID.
+func Camel(s string) string {
+	name := Pascal(s)
+	prefix := 0
+	for prefix < len(name) && unicode.IsUpper(rune(name[prefix])) {
+		prefix++
+	}
+	switch {
+	case prefix == len(name):
+		return strings.ToLower(name)
+	case prefix > 1:
+		// APIKey -> apiKey
+		return strings.ToLower(name[:prefix-1]) + name[prefix-1:]
+	default:
+		return strings.ToLower(name[:1]) + name[1:]
+	}
+}
+
+// GoIdent converts a name to an unexported Go name that is not a keyword:
+// userID, type_.
+func GoIdent(s string) string {
+	name := Camel(s)
+	if token.IsKeyword(name) {
+		name += "_"
+	}
+	return name
+}
+
+// Snake converts a name to lower case words joined by underscores: user_id.
+func Snake(s string) string {
+	return strings.ToLower(strings.Join(words(s), "_"))
+}
+
+// Kebab converts a name to lower case words joined by hyphens: user-id.
+func Kebab(s string) string {
+	return strings.ToLower(strings.Join(words(s), "-"))
+}
+
+// irregulars are the plurals of the nouns not following the rules of Plural.
+var irregulars = map[string]string{
+	"child":  "children",
+	"person": "people",
+	"status": "statuses",
+}
+
+// Plural returns the plural of an English noun: user, users; category,
+// categories; box, boxes.
+func Plural(s string) string {
+	lower := strings.ToLower(s)
+	for singular, plural := range irregulars {
+		if strings.HasSuffix(lower, singular) {
+			return s[:len(s)-len(singular)] + matchCase(plural, s[len(s)-len(singular):])
+		}
+	}
+	switch {
+	case s == "":
+		return s
+	case strings.HasSuffix(lower, "y") && len(s) > 1 && !isVowel(lower[len(lower)-2]):
+		return s[:len(s)-1] + matchCase("ies", s[len(s)-1:])
+	case strings.HasSuffix(lower, "s"), strings.HasSuffix(lower, "x"), strings.HasSuffix(lower, "z"),
+		strings.HasSuffix(lower, "ch"), strings.HasSuffix(lower, "sh"):
+		return s + matchCase("es", s[len(s)-1:])
+	default:
+		return s + matchCase("s", s[len(s)-1:])
+	}
+}
+
+// Singular returns the singular of an English noun, the reverse of Plural.
+func Singular(s string) string {
+	lower := strings.ToLower(s)
+	for singular, plural := range irregulars {
+		if strings.HasSuffix(lower, plural) {
+			return s[:len(s)-len(plural)] + matchCase(singular, s[len(s)-len(plural):])
+		}
+	}
+	switch {
+	case strings.HasSuffix(lower, "ies") && len(s) > 3:
+		return s[:len(s)-3] + matchCase("y", s[len(s)-3:])
+	case strings.HasSuffix(lower, "sses"), strings.HasSuffix(lower, "xes"), strings.HasSuffix(lower, "zzes"),
+		strings.HasSuffix(lower, "ches"), strings.HasSuffix(lower, "shes"):
+		return s[:len(s)-2]
+	case strings.HasSuffix(lower, "ss"), strings.HasSuffix(lower, "us"), strings.HasSuffix(lower, "is"):
+		return s
+	case strings.HasSuffix(lower, "s"):
+		return s[:len(s)-1]
+	default:
+		return s
+	}
+}
+
+// matchCase returns suffix in upper case if the word it ends is in upper
+// case, as in USERS.
+func matchCase(suffix, word string) string {
+	if word != strings.ToLower(word) && word == strings.ToUpper(word) {
+		return strings.ToUpper(suffix)
+	}
+	return suffix
+}
+
+func isVowel(c byte) bool {
+	return strings.IndexByte("aeiou", c) >= 0
+}
+
+// words splits a name at non-alphanumeric characters, at lower-to-upper
+// case changes and before the last letter of upper case runs followed by a
+// lower case letter: APIKey is API and Key.
+func words(s string) []string {
+	var words []string
+	var word []rune
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, string(word))
+			word = nil
+		}
+	}
+	runes := []rune(s)
+	for i, r := range runes {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+			continue
+		case unicode.IsUpper(r) && i > 0 && unicode.IsLower(runes[i-1]):
+			flush()
+		case unicode.IsUpper(r) && i > 0 && unicode.IsUpper(runes[i-1]) &&
+			i+1 < len(runes) && unicode.IsLower(runes[i+1]):
+			flush()
+		}
+		word = append(word, r)
+	}
+	flush()
+	return words
+}
+
+// toYAML encodes v as YAML, without the final newline, so a scalar can be
+// written inline: title: {{toYAML .ProjectName}}.
+func toYAML(v interface{}) (string, error) {
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(data), "\n"), nil
+}
+
+// indent indents the non-empty lines of s by n spaces.
+func indent(n int, s string) string {
+	pad := strings.Repeat(" ", n)
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = pad + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// join joins the elements of a slice, formatted with fmt, with sep.
+func join(sep string, list interface{}) (string, error) {
+	v := reflect.ValueOf(list)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return "", fmt.Errorf("join: %T is not a list", list)
+	}
+	elems := make([]string, v.Len())
+	for i := range elems {
+		elems[i] = fmt.Sprint(v.Index(i).Interface())
+	}
+	return strings.Join(elems, sep), nil
+}
+
+// defaultValue returns v, or def if v is empty.
+func defaultValue(def, v interface{}) interface{} {
+	if empty(v) {
+		return def
+	}
+	return v
+}
+
+// required returns v, or fails with msg if v is empty.
+func required(msg string, v interface{}) (interface{}, error) {
+	if empty(v) {
+		return nil, fmt.Errorf("%s", msg)
+	}
+	return v, nil
+}
+
+// empty reports whether v is nil, the zero value of its type or an empty
+// slice or map.
+func empty(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Array, reflect.String:
+		return rv.Len() == 0
+	default:
+		return rv.IsZero()
+	}
+}
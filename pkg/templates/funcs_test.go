@@ -0,0 +1,128 @@
+package templates
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNames(t *testing.T) {
+	tests := []struct {
+		in, pascal, camel, goIdent, snake, kebab string
+	}{
+		{in: "list_users", pascal: "ListUsers", camel: "listUsers", goIdent: "listUsers", snake: "list_users", kebab: "list-users"},
+		{in: "listUsers", pascal: "ListUsers", camel: "listUsers", goIdent: "listUsers", snake: "list_users", kebab: "list-users"},
+		{in: "user-id", pascal: "UserID", camel: "userID", goIdent: "userID", snake: "user_id", kebab: "user-id"},
+		{in: "ID", pascal: "ID", camel: "id", goIdent: "id", snake: "id", kebab: "id"},
+		{in: "api_key", pascal: "APIKey", camel: "apiKey", goIdent: "apiKey", snake: "api_key", kebab: "api-key"},
+		{in: "APIKey", pascal: "APIKey", camel: "apiKey", goIdent: "apiKey", snake: "api_key", kebab: "api-key"},
+		{in: "type", pascal: "Type", camel: "type", goIdent: "type_", snake: "type", kebab: "type"},
+		{in: "2fa", pascal: "X2fa", camel: "x2fa", goIdent: "x2fa", snake: "2fa", kebab: "2fa"},
+		{in: "my service", pascal: "MyService", camel: "myService", goIdent: "myService", snake: "my_service", kebab: "my-service"},
+	}
+
+	for _, tt := range tests {
+		for _, c := range []struct {
+			name string
+			fn   func(string) string
+			want string
+		}{
+			{"Pascal", Pascal, tt.pascal},
+			{"Camel", Camel, tt.camel},
+			{"GoIdent", GoIdent, tt.goIdent},
+			{"Snake", Snake, tt.snake},
+			{"Kebab", Kebab, tt.kebab},
+		} {
+			if got := c.fn(tt.in); got != c.want {
+				t.Errorf("%s(%q) = %q, want %q", c.name, tt.in, got, c.want)
+			}
+		}
+	}
+}
+
+func TestPlural(t *testing.T) {
+	tests := []struct {
+		singular, plural string
+	}{
+		{"user", "users"},
+		{"category", "categories"},
+		{"key", "keys"},
+		{"box", "boxes"},
+		{"address", "addresses"},
+		{"batch", "batches"},
+		{"status", "statuses"},
+		{"person", "people"},
+		{"API_KEY", "API_KEYS"},
+	}
+
+	for _, tt := range tests {
+		if got := Plural(tt.singular); got != tt.plural {
+			t.Errorf("Plural(%q) = %q, want %q", tt.singular, got, tt.plural)
+		}
+		if got := Singular(tt.plural); got != tt.singular {
+			t.Errorf("Singular(%q) = %q, want %q", tt.plural, got, tt.singular)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		data    interface{}
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "case",
+			content: `{{.Name | pascal}} {{.Name | plural | snake}}`,
+			data:    map[string]interface{}{"Name": "apiKey"},
+			want:    "APIKey api_keys",
+		},
+		{
+			name:    "default",
+			content: `{{.Name | default "app"}}`,
+			data:    map[string]interface{}{"Name": ""},
+			want:    "app",
+		},
+		{
+			name:    "required",
+			content: `{{required "the name is required" .Name}}`,
+			data:    map[string]interface{}{},
+			wantErr: true,
+		},
+		{
+			name:    "join",
+			content: `{{join ", " .Scopes}}`,
+			data:    map[string]interface{}{"Scopes": []string{"read", "write"}},
+			want:    "read, write",
+		},
+		{
+			name:    "yaml",
+			content: "config:\n{{toYAML .Config | indent 2}}",
+			data:    map[string]interface{}{"Config": map[string]int{"port": 8080}},
+			want:    "config:\n  port: 8080",
+		},
+		{
+			name:    "partial",
+			content: "{{define \"up\"}}\nCREATE TABLE t ();{{end}}{{define \"down\"}}\nDROP TABLE t;{{end}}{{template \"migration\" .}}\n",
+			want:    "-- +goose Up\n-- +goose StatementBegin\nCREATE TABLE t ();\n-- +goose StatementEnd\n\n-- +goose Down\n-- +goose StatementBegin\nDROP TABLE t;\n-- +goose StatementEnd\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := Parse(tt.name, tt.content, nil)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			var buf strings.Builder
+			err = tmpl.Execute(&buf, tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Execute() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && buf.String() != tt.want {
+				t.Errorf("Execute() = %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
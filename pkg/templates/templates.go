@@ -2,7 +2,37 @@ package templates
 
 import (
 	"embed"
+	"sync"
+	"text/template"
 )
 
 //go:embed all:files
 var FS embed.FS
+
+// partialsPattern matches the files of the partials shared by all templates.
+const partialsPattern = "files/partials/*.tmpl"
+
+var (
+	partialsOnce sync.Once
+	partials     *template.Template
+	partialsErr  error
+)
+
+// Parse parses content as the template name, with Funcs, funcs and the
+// partials of files/partials: the {{define}} blocks every template can
+// render with {{template}}. A template can define the blocks a partial
+// renders, such as the statements of the migration partial.
+func Parse(name, content string, funcs template.FuncMap) (*template.Template, error) {
+	partialsOnce.Do(func() {
+		partials, partialsErr = template.New("partials").Funcs(Funcs()).ParseFS(FS, partialsPattern)
+	})
+	if partialsErr != nil {
+		return nil, partialsErr
+	}
+
+	tmpl, err := partials.Clone()
+	if err != nil {
+		return nil, err
+	}
+	return tmpl.New(name).Funcs(funcs).Parse(content)
+}